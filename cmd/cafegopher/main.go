@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/edutko/cafegopher/scan"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "scan":
+		os.Exit(scanCmd(os.Args[2:]))
+	case "help", "-h", "-help", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [arguments]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "commands:\n")
+	fmt.Fprintf(os.Stderr, "  scan    report known deserialization gadget classes in serialized Java data\n")
+}
+
+// scanCmd returns 0 if nothing was found, 1 if any findings were reported and
+// 2 if an input could not be read or parsed.
+func scanCmd(args []string) int {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	minSeverity := fs.String("min-severity", "low", "minimum severity to report (low, medium, high, critical)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s scan [flags] [file ...]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Reads standard input if no files are given or a file is \"-\".\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	threshold, err := scan.ParseSeverity(*minSeverity)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	status := 0
+	s := scan.NewScanner()
+	for _, name := range files {
+		findings, err := scanFile(s, name)
+		for _, f := range findings {
+			if f.Severity >= threshold {
+				fmt.Printf("%s: %s\n", name, f)
+				if status == 0 {
+					status = 1
+				}
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			status = 2
+		}
+	}
+	return status
+}
+
+func scanFile(s *scan.Scanner, name string) ([]scan.Finding, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return s.ScanReader(r)
+}
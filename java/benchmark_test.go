@@ -0,0 +1,206 @@
+package java
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func BenchmarkDecoder_Decode(b *testing.B) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.ser"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, f := range files {
+		name := strings.TrimSuffix(filepath.Base(f), ".ser")
+		data := mustReadFile(name)
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := NewDecoder(bytes.NewReader(data)).Decode(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecoder_corpus(b *testing.B) {
+	corpus := syntheticCorpus(b, 100)
+	readers := []struct {
+		name string
+		new  func() io.Reader
+	}{
+		{"seekable", func() io.Reader { return bytes.NewReader(corpus) }},
+		{"unseekable", func() io.Reader { return struct{ io.Reader }{bytes.NewReader(corpus)} }},
+		{"bufio", func() io.Reader { return bufio.NewReader(struct{ io.Reader }{bytes.NewReader(corpus)}) }},
+	}
+
+	for _, r := range readers {
+		b.Run("Decode/"+r.name, func(b *testing.B) {
+			b.SetBytes(int64(len(corpus)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := NewDecoder(r.new()).DecodeAll(-1); !errors.Is(err, io.EOF) {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	for _, opts := range []struct {
+		name string
+		opts []Option
+	}{{"bytes", nil}, {"zero copy", []Option{WithZeroCopy()}}} {
+		b.Run("Decode/"+opts.name, func(b *testing.B) {
+			b.SetBytes(int64(len(corpus)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := NewBytesDecoder(corpus, opts.opts...).DecodeAll(-1); !errors.Is(err, io.EOF) {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	b.Run("Skip", func(b *testing.B) {
+		b.SetBytes(int64(len(corpus)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			d := NewDecoder(bytes.NewReader(corpus))
+			var err error
+			for err == nil {
+				_, err = d.Skip()
+			}
+			if !errors.Is(err, io.EOF) {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkFeedDecoder_corpus(b *testing.B) {
+	corpus := syntheticCorpus(b, 100)
+	b.SetBytes(int64(len(corpus)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f := NewFeedDecoder()
+		for off := 0; off < len(corpus); off += 4096 {
+			end := off + 4096
+			if end > len(corpus) {
+				end = len(corpus)
+			}
+			if _, err := f.Feed(corpus[off:end]); err != nil && err != ErrNeedMoreData {
+				b.Fatal(err)
+			}
+		}
+		f.Close()
+	}
+}
+
+func BenchmarkFeedDecoder_chunked(b *testing.B) {
+	// Each call to Feed completes at most a few strings of the array.
+	data := largeStringArray(8192)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f := NewFeedDecoder()
+		var contents []Content
+		for off := 0; off < len(data); off += 16 {
+			end := off + 16
+			if end > len(data) {
+				end = len(data)
+			}
+			c, err := f.Feed(data[off:end])
+			if err != nil && err != ErrNeedMoreData {
+				b.Fatal(err)
+			}
+			contents = append(contents, c...)
+		}
+		f.Close()
+		if len(contents) != 1 {
+			b.Fatalf("decoded %d elements", len(contents))
+		}
+	}
+}
+
+func BenchmarkDecoder_largeArray(b *testing.B) {
+	data := largeIntArray(1 << 20)
+	b.Run("reader", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewDecoder(bytes.NewReader(data)).Decode(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("zero copy", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewBytesDecoder(data, WithZeroCopy()).Decode(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// syntheticCorpus returns a stream containing every element in testdata n
+// times, followed by resets so that each copy assigns the same handles.
+func syntheticCorpus(b *testing.B, n int) []byte {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.ser"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	header := len(StreamMagic) + 2
+	corpus := []byte(StreamMagic + "\x00\x05")
+	for i := 0; i < n; i++ {
+		for _, f := range files {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				b.Fatal(err)
+			}
+			// Some files have trailing data after the element.
+			d := NewDecoder(bytes.NewReader(data))
+			if _, err := d.Decode(); err != nil {
+				b.Fatal(err)
+			}
+			corpus = append(corpus, data[header:d.InputOffset()]...)
+			corpus = append(corpus, byte(tcReset))
+		}
+	}
+	return corpus
+}
+
+// largeStringArray returns a stream containing a String array of the given
+// length.
+func largeStringArray(length int) []byte {
+	// The class descriptor of strings.ser, up to the array's length.
+	data := mustReadFile("strings")[:40]
+	data = binary.BigEndian.AppendUint32(data, uint32(length))
+	for i := 0; i < length; i++ {
+		data = append(data, byte(tcString), 0x00, 0x04)
+		data = append(data, fmt.Sprintf("%04d", i%10000)...)
+	}
+	return data
+}
+
+// largeIntArray returns a stream containing an int array of the given length.
+func largeIntArray(length int) []byte {
+	// The class descriptor of ints.ser, up to the array's length.
+	data := mustReadFile("ints")[:23]
+	data = binary.BigEndian.AppendUint32(data, uint32(length))
+	for i := 0; i < length; i++ {
+		data = binary.BigEndian.AppendUint32(data, uint32(i))
+	}
+	return data
+}
@@ -1,21 +1,5 @@
 package java
 
-import (
-	"reflect"
-
-	javalang "github.com/edutko/cafegopher/java/lang"
-)
-
-func AllowInt8ToByteCoercion() {
-	allowInt8ToByteCoercion = true
-}
-
-func PreventInt8ToByteCoercion() {
-	allowInt8ToByteCoercion = false
-}
-
-var allowInt8ToByteCoercion = true
-
 func castToBool(x any) (bool, bool) {
 	switch v := x.(type) {
 	case bool:
@@ -188,17 +172,17 @@ func castToUint(x any) (uint, bool) {
 	return 0, false
 }
 
-func castToUint8(x any) (uint8, bool) {
+func castToUint8(x any, allowInt8Coercion bool) (uint8, bool) {
 	switch v := x.(type) {
 	case uint8:
 		return v, true
 	case int8:
-		if allowInt8ToByteCoercion || v >= 0 {
+		if allowInt8Coercion || v >= 0 {
 			return uint8(v), true
 		}
 	case Object:
 		if val, ok := unmarshalWrappedPrimitive(v); ok {
-			return castToUint8(val)
+			return castToUint8(val, allowInt8Coercion)
 		}
 	}
 	return 0, false
@@ -300,44 +284,35 @@ func castToUint64(x any) (uint64, bool) {
 	return 0, false
 }
 
+// unmarshalWrappedPrimitive returns the value held by an instance of one of
+// the java.lang wrapper classes (Boolean, Integer, etc.).
 func unmarshalWrappedPrimitive(object Object) (any, bool) {
-	switch object.ClassDesc.ClassName {
+	className := object.GetClassName()
+	v, ok := object.ClassData[className]["value"]
+	if !ok {
+		return nil, false
+	}
+	switch className {
 	case "java.lang.Boolean":
-		var v javalang.Boolean
-		if err := unmarshalValue(object, reflect.ValueOf(&v)); err == nil {
-			return v.Value, true
+		if b, ok := v.(bool); ok {
+			return b, true
 		}
 	case "java.lang.Byte":
-		var v javalang.Byte
-		if err := unmarshalValue(object, reflect.ValueOf(&v)); err == nil {
-			return v.Value, true
+		return castToInt8(v)
+	case "java.lang.Character":
+		if c, ok := v.(rune); ok {
+			return c, true
 		}
 	case "java.lang.Double":
-		var v javalang.Double
-		if err := unmarshalValue(object, reflect.ValueOf(&v)); err == nil {
-			return v.Value, true
-		}
+		return castToFloat64(v)
 	case "java.lang.Float":
-		var v javalang.Float
-		if err := unmarshalValue(object, reflect.ValueOf(&v)); err == nil {
-			return v.Value, true
-		}
+		return castToFloat32(v)
 	case "java.lang.Integer":
-		var v javalang.Integer
-		if err := unmarshalValue(object, reflect.ValueOf(&v)); err == nil {
-			return v.Value, true
-		}
+		return castToInt(v)
 	case "java.lang.Long":
-		var v javalang.Long
-		if err := unmarshalValue(object, reflect.ValueOf(&v)); err == nil {
-			return v.Value, true
-		}
+		return castToInt64(v)
 	case "java.lang.Short":
-		var v javalang.Short
-		if err := unmarshalValue(object, reflect.ValueOf(&v)); err == nil {
-			return v.Value, true
-		}
+		return castToInt16(v)
 	}
-
 	return nil, false
 }
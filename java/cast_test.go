@@ -451,6 +451,29 @@ func Test_castToUint(t *testing.T) {
 	}
 }
 
+func Test_castToUint8_withoutCoercion(t *testing.T) {
+	testCases := []struct {
+		value    any
+		ok       okay
+		expected uint8
+	}{
+		{int8(127), isOk, 127},
+		{uint8(255), isOk, 255},
+		{javaByte(127), isOk, 127},
+
+		{int8(-1), isNotOk, 0},
+		{javaByte(-1), isNotOk, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(stringify(tc.value), func(t *testing.T) {
+			actual, ok := castToUint8(tc.value, false)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, bool(tc.ok), ok)
+		})
+	}
+}
+
 func Test_castToUint8(t *testing.T) {
 	testCases := []struct {
 		value    any
@@ -493,7 +516,7 @@ func Test_castToUint8(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(stringify(tc.value), func(t *testing.T) {
-			actual, ok := castToUint8(tc.value)
+			actual, ok := castToUint8(tc.value, true)
 			assert.Equal(t, tc.expected, actual)
 			assert.Equal(t, bool(tc.ok), ok)
 		})
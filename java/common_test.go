@@ -94,6 +94,24 @@ var byteArray = Class{
 	Info:             ClassDescInfo{Flags: 0x02},
 }
 
+var charArray = Class{
+	ClassName:        "[C",
+	SerialVersionUID: -5753798564021173076,
+	Info:             ClassDescInfo{Flags: 0x02},
+}
+
+var intArray = Class{
+	ClassName:        "[I",
+	SerialVersionUID: 5600894804908749477,
+	Info:             ClassDescInfo{Flags: 0x02},
+}
+
+var intArray2 = Class{
+	ClassName:        "[[I",
+	SerialVersionUID: 1727100010502261052,
+	Info:             ClassDescInfo{Flags: 0x02},
+}
+
 var stringArray = Class{
 	ClassName:        "[Ljava.lang.String;",
 	SerialVersionUID: -5921575005990323385,
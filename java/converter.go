@@ -0,0 +1,74 @@
+package java
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type converterKey struct {
+	className string
+	goType    reflect.Type
+}
+
+type converter func(c Content) (reflect.Value, error)
+
+// RegisterConverter registers a function that converts instances of
+// className (or of its subclasses) into values of type T. Unmarshal consults
+// converters before anything else, including Unmarshaler implementations such
+// as the types of the java/lang package. Strings can be converted by
+// registering for "java.lang.String".
+func RegisterConverter[T any](r *Registry, className string, convert func(c Content) (T, error)) {
+	goType := reflect.TypeOf((*T)(nil)).Elem()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.convs[converterKey{className, goType}] = func(c Content) (reflect.Value, error) {
+		v, err := convert(c)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(&v).Elem(), nil
+	}
+}
+
+func (r *Registry) converterFor(classNames []string, goType reflect.Type) (converter, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, name := range classNames {
+		if conv, ok := r.convs[converterKey{name, goType}]; ok {
+			return conv, true
+		}
+	}
+	return nil, false
+}
+
+// convert applies the converter registered for javaValue's class and
+// goValue's type, if any.
+func (u *unmarshaler) convert(javaValue Content, goValue reflect.Value) (bool, error) {
+	var classNames []string
+	if _, ok := javaValue.(string); ok {
+		classNames = []string{"java.lang.String"}
+	}
+	for cd := classDescOf(javaValue); cd != nil; cd = cd.Info.SuperClassDesc {
+		classNames = append(classNames, cd.ClassName)
+	}
+	if len(classNames) == 0 {
+		return false, nil
+	}
+
+	conv, ok := DefaultRegistry.converterFor(classNames, goValue.Type())
+	if u.opts.registry != nil {
+		if c, found := u.opts.registry.converterFor(classNames, goValue.Type()); found {
+			conv, ok = c, true
+		}
+	}
+	if !ok {
+		return false, nil
+	}
+
+	v, err := conv(javaValue)
+	if err != nil {
+		return true, fmt.Errorf("convert %s to %s: %w", classNames[0], goValue.Type(), err)
+	}
+	goValue.Set(v)
+	return true, nil
+}
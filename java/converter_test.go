@@ -0,0 +1,96 @@
+package java
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterConverter(t *testing.T) {
+	r := NewRegistry()
+	RegisterConverter(r, "java.util.concurrent.atomic.AtomicLong", func(c Content) (int64, error) {
+		v, _ := castToInt64(c.(Object).ClassData["java.util.concurrent.atomic.AtomicLong"]["value"])
+		return v, nil
+	})
+	RegisterConverter(r, "com.acme.Circle", func(c Content) (float64, error) {
+		var ci circle
+		err := UnmarshalContent(c, &ci)
+		return ci.Area(), err
+	})
+	RegisterConverter(r, "java.lang.String", func(c Content) (upper, error) {
+		return upper(strings.ToUpper(c.(string))), nil
+	})
+
+	t.Run("value class", func(t *testing.T) {
+		var actual int64
+		err := newUnmarshaler(WithRegistry(r)).unmarshalValue(atomicLong, reflect.ValueOf(&actual))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(42), actual)
+	})
+
+	t.Run("superclass", func(t *testing.T) {
+		var actual float64
+		err := newUnmarshaler(WithRegistry(r)).unmarshalValue(bigCircle, reflect.ValueOf(&actual))
+		assert.Nil(t, err)
+		assert.Equal(t, circle{Radius: 100}.Area(), actual)
+	})
+
+	t.Run("string", func(t *testing.T) {
+		var actual []upper
+		err := newUnmarshaler(WithRegistry(r)).unmarshalValue(Array{Values: []Value{"a", "b"}}, reflect.ValueOf(&actual))
+		assert.Nil(t, err)
+		assert.Equal(t, []upper{"A", "B"}, actual)
+	})
+
+	t.Run("pointer", func(t *testing.T) {
+		var actual *int64
+		err := newUnmarshaler(WithRegistry(r)).unmarshalValue(atomicLong, reflect.ValueOf(&actual))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(42), *actual)
+	})
+
+	t.Run("other target type", func(t *testing.T) {
+		var actual string
+		err := newUnmarshaler(WithRegistry(r)).unmarshalValue("a", reflect.ValueOf(&actual))
+		assert.Nil(t, err)
+		assert.Equal(t, "a", actual)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		r := NewRegistry()
+		RegisterConverter(r, "java.util.concurrent.atomic.AtomicLong", func(c Content) (int64, error) {
+			return 0, errors.New("oops")
+		})
+		var actual int64
+		err := newUnmarshaler(WithRegistry(r)).unmarshalValue(atomicLong, reflect.ValueOf(&actual))
+		assert.Equal(t, "convert java.util.concurrent.atomic.AtomicLong to int64: oops", err.Error())
+	})
+
+	t.Run("default registry", func(t *testing.T) {
+		RegisterConverter(DefaultRegistry, "com.acme.Square", func(c Content) (string, error) {
+			return "square", nil
+		})
+		defer delete(DefaultRegistry.convs, converterKey{"com.acme.Square", reflect.TypeOf("")})
+
+		var actual []string
+		err := newUnmarshaler().unmarshalValue(Array{Values: []Value{shapeList.Values[1]}}, reflect.ValueOf(&actual))
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"square"}, actual)
+	})
+}
+
+type upper string
+
+var atomicLong = Object{
+	ClassDesc: &Class{
+		ClassName: "java.util.concurrent.atomic.AtomicLong",
+		Info: ClassDescInfo{
+			Flags:  0x02,
+			Fields: []Field{{TypeLong, "value", ""}},
+		},
+	},
+	ClassData: ClassData{"java.util.concurrent.atomic.AtomicLong": {"value": int64(42)}},
+}
@@ -2,10 +2,14 @@ package java
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"reflect"
+	"strings"
 )
 
 const (
@@ -13,12 +17,51 @@ const (
 	StreamVersion = int16(5)
 )
 
-func NewDecoder(r io.Reader) *Decoder {
-	d := &Decoder{r: &binaryReader{r}}
+// NewDecoder returns a decoder that reads from r. The decoder consumes exactly
+// the bytes of the elements it decodes, so r can be used to continue reading
+// an enclosing format once the decoder is done with it. If r implements
+// io.Seeker, the decoder reads it in larger chunks and seeks back over the
+// unused bytes before returning; other readers are never read ahead, and are
+// best wrapped in a bufio.Reader if the decoder is to have them to itself.
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	return newDecoder(r, newOptions(opts))
+}
+
+func newDecoder(r io.Reader, opts options) *Decoder {
+	d := &Decoder{r: newBinaryReader(r), opts: opts}
 	d.Reset()
 	return d
 }
 
+// NewBytesDecoder returns a decoder that reads data, such as a memory-mapped
+// file, in place. With WithZeroCopy, block data and the contents of primitive
+// arrays share memory with data instead of being copied.
+func NewBytesDecoder(data []byte, opts ...Option) *Decoder {
+	return newBytesDecoder(data, newOptions(opts))
+}
+
+func newBytesDecoder(data []byte, opts options) *Decoder {
+	d := &Decoder{r: newBytesReader(data), opts: opts}
+	d.Reset()
+	return d
+}
+
+// NewReaderAtDecoder returns a decoder that reads the size bytes of r that
+// start at off. Since r is only read with ReadAt, several decoders can read
+// different parts of r at once. The input is read into windows that are never
+// reused, so with WithZeroCopy, block data and the contents of primitive
+// arrays are sub-slices of those windows instead of copies.
+func NewReaderAtDecoder(r io.ReaderAt, off, size int64, opts ...Option) *Decoder {
+	d := &Decoder{r: newWindowReader(r, off, size), opts: newOptions(opts)}
+	d.Reset()
+	return d
+}
+
+// zeroCopy reports whether values may share memory with the input.
+func (d *Decoder) zeroCopy() bool {
+	return d.opts.zeroCopy && (d.r.inMemory || d.r.windowed)
+}
+
 // Decode parses the first element of a stream of serialized Java objects
 // https://docs.oracle.com/javase/6/docs/platform/serialization/spec/protocol.html
 func (d *Decoder) Decode() (Content, error) {
@@ -32,26 +75,134 @@ func (d *Decoder) Decode() (Content, error) {
 	return nil, nil
 }
 
+// DecodeContext is like Decode, but gives up with ctx.Err() once ctx is done.
+// The decoder checks ctx between elements, array values and classes of an
+// object, so a single primitive array or string is read in full.
+func (d *Decoder) DecodeContext(ctx context.Context) (Content, error) {
+	d.ctx = ctx
+	defer func() { d.ctx = nil }()
+	return d.Decode()
+}
+
+// checkContext returns the error of the context passed to DecodeContext, if
+// it is done.
+func (d *Decoder) checkContext() error {
+	if d.ctx == nil {
+		return nil
+	}
+	return d.ctx.Err()
+}
+
 // DecodeAll parses serialized Java objects
 // https://docs.oracle.com/javase/6/docs/platform/serialization/spec/protocol.html
-func (d *Decoder) DecodeAll(limit int) ([]Content, error) {
-	magic, err := d.r.readBytes(2)
+func (d *Decoder) DecodeAll(limit int) (contents []Content, err error) {
+	defer d.release(&err)
+	if err := d.readStreamHeader(); err != nil {
+		return nil, err
+	}
+
+	return d.readContents(limit)
+}
+
+// release hands back any input that was read ahead; see binaryReader. It is
+// deferred by the methods that read from the stream, and sets *err if there
+// was no error yet.
+func (d *Decoder) release(err *error) {
+	if rerr := d.r.release(); rerr != nil && *err == nil {
+		*err = fmt.Errorf("release: %w", rerr)
+	}
+}
+
+// InputOffset returns the number of bytes the decoder has consumed. This is
+// the offset of the end of the most recently decoded element, relative to
+// where the decoder started reading.
+func (d *Decoder) InputOffset() int64 {
+	return d.r.n
+}
+
+// Skip reads past the next element of the stream without building it, and
+// returns the number of bytes consumed. Handles are assigned as in Decode, so
+// later elements can still refer to the class descriptors, strings and enum
+// constants in the skipped element; references to skipped objects and arrays
+// decode as values without data.
+func (d *Decoder) Skip() (n int64, err error) {
+	defer d.release(&err)
+	start := d.r.n
+	if err := d.readStreamHeader(); err != nil {
+		return d.r.n - start, err
+	}
+
+	d.skipping = true
+	_, err = d.readTopLevelContent()
+	d.skipping = false
 	if err != nil {
-		return nil, fmt.Errorf("readBytes: %w", err)
+		return d.r.n - start, fmt.Errorf("d.readContent: %w", err)
+	}
+	return d.r.n - start, nil
+}
+
+// Unmarshal decodes the next element of the stream and stores it in the value
+// pointed to by v, following the rules of the package-level Unmarshal.
+func (d *Decoder) Unmarshal(v any) error {
+	return (&unmarshaler{opts: d.opts}).unmarshalNext(d, v)
+}
+
+// UnmarshalContext is like Unmarshal, but gives up with ctx.Err() once ctx is
+// done; see DecodeContext.
+func (d *Decoder) UnmarshalContext(ctx context.Context, v any) error {
+	d.ctx = ctx
+	defer func() { d.ctx = nil }()
+	return d.Unmarshal(v)
+}
+
+// DisallowUnknownFields causes Unmarshal to return an error when an object
+// contains a field that is not mapped to any field of the destination struct.
+func (d *Decoder) DisallowUnknownFields() {
+	d.opts.disallowUnknownFields = true
+}
+
+func (d *Decoder) readHeader() error {
+	// stream:
+	//   magic version contents
+	magic, err := d.r.readView(2)
+	if err != nil {
+		return fmt.Errorf("readView: %w", err)
 	}
 	if !bytes.Equal(magic, []byte(StreamMagic)) {
-		return nil, fmt.Errorf("invalid stream: incorrect magic")
+		return fmt.Errorf("invalid stream: incorrect magic")
 	}
 
 	version, err := d.r.readInt16()
 	if err != nil {
-		return nil, fmt.Errorf("readInt16: %w", err)
+		return fmt.Errorf("readInt16: %w", err)
 	}
 	if version != StreamVersion {
-		return nil, fmt.Errorf("unsupported serialization version: %d", version)
+		return fmt.Errorf("unsupported serialization version: %d", version)
 	}
 
-	return d.readContents(limit)
+	d.headerRead = true
+	return nil
+}
+
+// readStreamHeader reads the stream header if it has not been read yet, or if
+// another stream starts here. Each ObjectOutputStream writes its own header,
+// and formats such as JCEKS keystores write streams one after another. The
+// magic cannot start an element, so only one byte needs to be looked at.
+//
+// Handles are numbered from the start of each stream, so the handle table is
+// reset after each header.
+func (d *Decoder) readStreamHeader() error {
+	if d.headerRead {
+		b, err := d.r.peek(1)
+		if err != nil || b[0] != StreamMagic[0] {
+			return nil
+		}
+	}
+	if err := d.readHeader(); err != nil {
+		return err
+	}
+	d.Reset()
+	return nil
 }
 
 func (d *Decoder) Reset() {
@@ -63,6 +214,26 @@ type Decoder struct {
 	r *binaryReader
 	h handle
 	o map[handle]Content
+
+	opts       options
+	ctx        context.Context
+	handler    Handler
+	headerRead bool
+	skipping   bool
+	// reading holds the arrays whose values are being read, and whether they
+	// have been referenced since; see fixSelfReferences.
+	reading map[handle]bool
+
+	// count is the number of top-level elements read so far.
+	count int
+	// spans, open and path are used to record spans; see WithSpans.
+	spans []Span
+	open  []int
+	path  []string
+
+	// diagnostics and failure are used in recovery mode; see WithRecovery.
+	diagnostics []Diagnostic
+	failure     *Diagnostic
 }
 
 func (d *Decoder) readContents(limit int) ([]Content, error) {
@@ -74,7 +245,26 @@ func (d *Decoder) readContents(limit int) ([]Content, error) {
 	}
 	var contents []Content
 	for i := 0; i < limit; i++ {
-		c, err := d.readContent()
+		if err := d.checkContext(); err != nil {
+			return contents, err
+		}
+		if err := d.readStreamHeader(); err != nil {
+			return contents, err
+		}
+		start := d.r.n
+		c, err := d.readTopLevelContent()
+		if err != nil && d.opts.recover && d.r.n > start && d.checkContext() == nil {
+			// Keep whatever was read and carry on; a clean end of input
+			// is still reported as an error. Bytes discarded without
+			// producing anything do not count towards the limit.
+			if isPartial(c) {
+				contents = append(contents, c)
+			} else {
+				i--
+			}
+			d.recoverFrom(err)
+			continue
+		}
 		if err != nil {
 			return contents, fmt.Errorf("d.readContent: %w", err)
 		}
@@ -85,7 +275,17 @@ func (d *Decoder) readContents(limit int) ([]Content, error) {
 	return contents, nil
 }
 
-func (d *Decoder) readContent() (Content, error) {
+func (d *Decoder) readTopLevelContent() (Content, error) {
+	d.pushIndex(d.count)
+	defer d.popPath()
+	c, err := d.readContent()
+	if c != nil && (err == nil || isPartial(c)) {
+		d.count++
+	}
+	return c, err
+}
+
+func (d *Decoder) readContent() (c Content, err error) {
 	// content:
 	//   object
 	//   blockdata
@@ -102,10 +302,17 @@ func (d *Decoder) readContent() (Content, error) {
 	//   TC_RESET
 	// nullReference:
 	//   TC_NULL
+	if d.opts.recover {
+		defer func() { d.noteError(err) }()
+	}
+	start := d.r.n
 	t, err := d.r.readTypeCode()
 	if err != nil {
 		return nil, fmt.Errorf("readTypeCode: %w", err)
 	}
+	if kind, ok := spanKinds[t]; ok && d.opts.recordSpans {
+		defer d.closeSpan(d.openSpan(kind, start))
+	}
 
 	switch t {
 	case tcObject:
@@ -127,6 +334,9 @@ func (d *Decoder) readContent() (Content, error) {
 	case tcReference:
 		return d.readReference()
 	case tcNull:
+		if d.handler != nil {
+			return nil, d.handler.Null()
+		}
 		return nil, nil
 	case tcException:
 		return d.readException()
@@ -147,11 +357,17 @@ func (d *Decoder) readContent() (Content, error) {
 func (d *Decoder) readNewClass() (*Class, error) {
 	// newClass:
 	//   TC_CLASS classDesc newHandle
-	c, err := d.readClassDesc()
+	c, err := d.readOwnClassDesc()
 	if err != nil {
 		return c, fmt.Errorf("d.readClassDesc: %w", err)
 	}
-	d.o[d.newHandle()] = c
+	h := d.newHandle()
+	d.o[h] = c
+	if d.handler != nil {
+		if err := d.handler.Class(c, int(h)); err != nil {
+			return c, err
+		}
+	}
 	return c, nil
 }
 
@@ -160,10 +376,15 @@ func (d *Decoder) readClassDesc() (*Class, error) {
 	//   newClassDesc
 	//   nullReference
 	//   (ClassDesc)prevObject  // an object required to be of type ClassDesc
+	defer d.muteHandler()()
+	start := d.r.n
 	t, err := d.r.readTypeCode()
 	if err != nil {
 		return nil, fmt.Errorf("readTypeCode: %w", err)
 	}
+	if t != tcNull && d.opts.recordSpans {
+		defer d.closeSpan(d.openSpan(spanKinds[t], start))
+	}
 
 	switch t {
 	case tcClassDesc:
@@ -191,6 +412,16 @@ func (d *Decoder) readClassDesc() (*Class, error) {
 func (d *Decoder) readSuperClassDesc() (*Class, error) {
 	// superClassDesc:
 	//   classDesc
+	d.pushPath("[super]")
+	defer d.popPath()
+	return d.readClassDesc()
+}
+
+// readOwnClassDesc reads the class descriptor of an object, array, enum or
+// class.
+func (d *Decoder) readOwnClassDesc() (*Class, error) {
+	d.pushPath("[class]")
+	defer d.popPath()
 	return d.readClassDesc()
 }
 
@@ -198,6 +429,7 @@ func (d *Decoder) readNewClassDesc() (Class, error) {
 	// newClassDesc:
 	//   TC_CLASSDESC className serialVersionUID newHandle classDescInfo
 	//   TC_PROXYCLASSDESC newHandle proxyClassDescInfo
+	defer d.muteHandler()()
 	var err error
 	c := Class{}
 	c.ClassName, err = d.readString()
@@ -292,7 +524,9 @@ func (d *Decoder) readField() (Field, error) {
 		return f, fmt.Errorf("d.readNewString: %w", err)
 	}
 	if f.TypeCode.IsObject() {
+		d.pushPath(f.FieldName)
 		c, err := d.readContent()
+		d.popPath()
 		if err != nil {
 			return f, fmt.Errorf("d.readContent: %w", err)
 		}
@@ -308,6 +542,8 @@ func (d *Decoder) readClassAnnotation() ([]Annotation, error) {
 	// classAnnotation:
 	//	 endBlockData
 	//	 contents endBlockData  // contents written by annotateClass
+	d.pushPath("[annotation]")
+	defer d.popPath()
 	return d.readAnnotation()
 }
 
@@ -316,7 +552,7 @@ func (d *Decoder) readNewArray() (Array, error) {
 	//   TC_ARRAY classDesc newHandle (int)<size> values[size]
 	var err error
 	a := Array{}
-	a.ClassDesc, err = d.readClassDesc()
+	a.ClassDesc, err = d.readOwnClassDesc()
 	if err != nil {
 		return a, fmt.Errorf("d.readClassDesc: %w", err)
 	}
@@ -325,16 +561,273 @@ func (d *Decoder) readNewArray() (Array, error) {
 	if err != nil {
 		return a, fmt.Errorf("readInt32: %w", err)
 	}
+	if count < 0 {
+		return a, fmt.Errorf("invalid array size: %d", count)
+	}
+	// Like an object, the array is registered before its values are read,
+	// and kept up to date as they are, so that they can refer back to it.
+	d.o[h] = a
+	if d.handler != nil {
+		if err := d.handler.StartArray(a.ClassDesc, int(h), int(count)); err != nil {
+			return a, err
+		}
+	}
 	t, _ := a.ItemType()
+	if t == 0 && a.ClassDesc != nil && isReferenceArrayName(a.ClassDesc.ClassName) {
+		// The name does not parse, as with the names of hidden classes, but
+		// its elements are still references.
+		t = TypeObject
+	}
+	if a.ClassDesc != nil && len(a.ClassDesc.ClassName) == 2 && t.IsPrimitive() {
+		if err := d.checkContext(); err != nil {
+			return a, err
+		}
+		if d.skipping && d.handler == nil {
+			return a, d.skipPrimitiveArray(t, int(count))
+		}
+		if a.Data, a.Raw, err = d.readPrimitiveArray(t, int(count)); err != nil {
+			return a, fmt.Errorf("d.readPrimitiveArray: %w", err)
+		}
+		if a.Raw != nil {
+			a.rawType = t
+		}
+		if d.handler != nil {
+			for i := 0; i < a.Length(); i++ {
+				if err := d.handler.Primitive(a.Get(i)); err != nil {
+					return a, err
+				}
+			}
+			if d.skipping {
+				a.Data, a.Raw = nil, nil
+			}
+		}
+		d.o[h] = a
+		return a, d.endArray()
+	}
+	// Values are preallocated for short arrays, so that references to the
+	// array from its own values share their storage.
+	if !d.skipping && count > 0 {
+		c := count
+		if c > maxArrayPrealloc {
+			c = maxArrayPrealloc
+		}
+		a.Values = make([]Value, 0, c)
+		d.o[h] = a
+	}
+	// References to the array from its own values only see the values read
+	// so far, in storage that longer arrays outgrow, until they are fixed up.
+	var outgrown []uintptr
+	if !d.skipping && count > 0 {
+		if d.reading == nil {
+			d.reading = make(map[handle]bool)
+		}
+		d.reading[h] = false
+		defer delete(d.reading, h)
+	}
 	for i := 0; i < int(count); i++ {
+		if err := d.checkContext(); err != nil {
+			return a, err
+		}
+		d.pushIndex(i)
 		v, err := d.readValue(t)
+		d.popPath()
 		if err != nil && !errors.Is(err, ErrNotSupported) {
+			if d.opts.recover {
+				if isPartial(v) {
+					a.Values = append(a.Values, v)
+				}
+				a.Incomplete = true
+			}
 			return a, fmt.Errorf("d.readValue: %w", err)
 		}
-		a.Values = append(a.Values, v)
+		if !d.skipping {
+			if len(a.Values) == cap(a.Values) {
+				outgrown = append(outgrown, reflect.ValueOf(a.Values).Pointer())
+			}
+			a.Values = append(a.Values, v)
+			d.o[h] = a
+		}
+	}
+	if d.reading[h] {
+		fixSelfReferences(a, outgrown)
+	}
+	return a, d.endArray()
+}
+
+// isReferenceArrayName reports whether name is the name of an array of
+// objects or of arrays.
+func isReferenceArrayName(name string) bool {
+	return strings.HasPrefix(name, "[L") || strings.HasPrefix(name, "[[")
+}
+
+// fixSelfReferences replaces the copies of a held by its values, taken by
+// references to a while it was read, with a itself. The copies only see the
+// values read before them, in a's current storage or in storage it outgrew.
+func fixSelfReferences(a Array, outgrown []uintptr) {
+	final := reflect.ValueOf(a.Values).Pointer()
+	isCopy := func(v Value) bool {
+		c, ok := v.(Array)
+		if !ok || cap(c.Values) == 0 {
+			return false
+		}
+		p := reflect.ValueOf(c.Values).Pointer()
+		if p == final {
+			return true
+		}
+		for _, o := range outgrown {
+			if p == o {
+				return true
+			}
+		}
+		return false
+	}
+	seen := make(map[uintptr]bool)
+	var fix func(v Value) Value
+	fix = func(v Value) Value {
+		if isCopy(v) {
+			return a
+		}
+		switch c := v.(type) {
+		case Object:
+			p := reflect.ValueOf(c.ClassData).Pointer()
+			if c.ClassData == nil || seen[p] {
+				break
+			}
+			seen[p] = true
+			for _, fields := range c.ClassData {
+				for name, f := range fields {
+					fields[name] = fix(f)
+				}
+			}
+		case Array:
+			p := reflect.ValueOf(c.Values).Pointer()
+			if len(c.Values) == 0 || seen[p] {
+				break
+			}
+			seen[p] = true
+			for i := range c.Values {
+				c.Values[i] = fix(c.Values[i])
+			}
+		case []Annotation:
+			for i := range c {
+				c[i] = fix(c[i])
+			}
+		}
+		return v
+	}
+	for i := range a.Values {
+		a.Values[i] = fix(a.Values[i])
+	}
+}
+
+func (d *Decoder) endArray() error {
+	if d.handler == nil {
+		return nil
+	}
+	return d.handler.EndArray()
+}
+
+// maxArrayPrealloc limits the number of values preallocated for an array, so
+// that a corrupt length cannot exhaust memory.
+const maxArrayPrealloc = 64
+
+var primitiveSizes = map[TypeCode]int64{
+	TypeByte: 1, TypeBoolean: 1, TypeChar: 2, TypeShort: 2,
+	TypeInteger: 4, TypeFloat: 4, TypeLong: 8, TypeDouble: 8,
+}
+
+// readPrimitiveArray reads the values of a primitive array in one go and
+// returns them as a typed slice, as described for Array.Data. When decoding
+// with WithZeroCopy, it returns the encoded values instead, as described for
+// Array.Raw.
+func (d *Decoder) readPrimitiveArray(t TypeCode, count int) (any, []byte, error) {
+	size, ok := primitiveSizes[t]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown type code: '%c'", t)
+	}
+	if d.zeroCopy() {
+		raw, err := d.r.readView(int64(count) * size)
+		if err != nil {
+			return nil, nil, fmt.Errorf("readView: %w", err)
+		}
+		if t == TypeByte {
+			return raw, raw, nil
+		}
+		return nil, raw, nil
+	}
+	if t == TypeByte {
+		b, err := d.r.readBytes(int64(count))
+		if err != nil {
+			return nil, nil, fmt.Errorf("readBytes: %w", err)
+		}
+		return b, nil, nil
+	}
+	b, err := d.r.readView(int64(count) * size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("readView: %w", err)
+	}
+	return decodePrimitives(t, b), nil, nil
+}
+
+// decodePrimitives decodes the big-endian values of type t in b.
+func decodePrimitives(t TypeCode, b []byte) any {
+	switch t {
+	case TypeByte:
+		return b
+	case TypeBoolean:
+		v := make([]bool, len(b))
+		for i := range v {
+			v[i] = b[i] != 0
+		}
+		return v
+	case TypeChar:
+		v := make([]uint16, len(b)/2)
+		for i := range v {
+			v[i] = binary.BigEndian.Uint16(b[2*i:])
+		}
+		return v
+	case TypeShort:
+		v := make([]int16, len(b)/2)
+		for i := range v {
+			v[i] = int16(binary.BigEndian.Uint16(b[2*i:]))
+		}
+		return v
+	case TypeInteger:
+		v := make([]int32, len(b)/4)
+		for i := range v {
+			v[i] = int32(binary.BigEndian.Uint32(b[4*i:]))
+		}
+		return v
+	case TypeFloat:
+		v := make([]float32, len(b)/4)
+		for i := range v {
+			v[i] = math.Float32frombits(binary.BigEndian.Uint32(b[4*i:]))
+		}
+		return v
+	case TypeLong:
+		v := make([]int64, len(b)/8)
+		for i := range v {
+			v[i] = int64(binary.BigEndian.Uint64(b[8*i:]))
+		}
+		return v
+	default:
+		v := make([]float64, len(b)/8)
+		for i := range v {
+			v[i] = math.Float64frombits(binary.BigEndian.Uint64(b[8*i:]))
+		}
+		return v
 	}
-	d.o[h] = a
-	return a, nil
+}
+
+func (d *Decoder) skipPrimitiveArray(t TypeCode, count int) error {
+	size, ok := primitiveSizes[t]
+	if !ok {
+		return fmt.Errorf("unknown type code: '%c'", t)
+	}
+	if err := d.r.skip(int64(count) * size); err != nil {
+		return fmt.Errorf("skip: %w", err)
+	}
+	return nil
 }
 
 func (d *Decoder) readNewObject() (Object, error) {
@@ -342,20 +835,33 @@ func (d *Decoder) readNewObject() (Object, error) {
 	//   TC_OBJECT classDesc newHandle classdata[]  // data for each class
 	var err error
 	o := Object{}
-	o.ClassDesc, err = d.readClassDesc()
+	o.ClassDesc, err = d.readOwnClassDesc()
 	if err != nil {
 		return o, fmt.Errorf("d.readClassDesc: %w", err)
 	}
+	// The object is registered before its fields are read, so that they can
+	// refer back to it. ClassData is filled in place.
+	if !d.skipping {
+		o.ClassData = make(ClassData)
+	}
 	h := d.newHandle()
-	o.ClassData, err = d.readClassData(o.ClassDesc)
-	if err != nil {
+	d.o[h] = o
+	if d.handler != nil {
+		if err := d.handler.StartObject(o.ClassDesc, int(h)); err != nil {
+			return o, err
+		}
+	}
+	if err = d.readClassData(o.ClassDesc, o.ClassData); err != nil {
+		o.Incomplete = d.opts.recover
 		return o, fmt.Errorf("d.readClassData: %w", err)
 	}
-	d.o[h] = o
+	if d.handler != nil {
+		return o, d.handler.EndObject()
+	}
 	return o, nil
 }
 
-func (d *Decoder) readClassData(classDesc *Class) (ClassData, error) {
+func (d *Decoder) readClassData(classDesc *Class, classesData ClassData) error {
 	// classdata:
 	//   nowrclass                 // SC_SERIALIZABLE & classDescFlag && !(SC_WRITE_METHOD & classDescFlags)
 	//   wrclass objectAnnotation  // SC_SERIALIZABLE & classDescFlag && SC_WRITE_METHOD & classDescFlags
@@ -374,38 +880,73 @@ func (d *Decoder) readClassData(classDesc *Class) (ClassData, error) {
 	// externalContent:          // Only parseable by readExternal
 	//   (bytes)                 // primitive data
 	//   object
-	var classDescs []Class
+	var classDescs []*Class
 	for cd := classDesc; cd != nil; cd = cd.Info.SuperClassDesc {
-		classDescs = append(classDescs, *cd)
+		classDescs = append(classDescs, cd)
 	}
 
-	classesData := make(ClassData)
 	for i := len(classDescs) - 1; i >= 0; i-- {
+		if err := d.checkContext(); err != nil {
+			return err
+		}
 		desc := classDescs[i]
-		classData := make(map[string]Value)
+		var classData map[string]Value
+		if !d.skipping {
+			classData = make(map[string]Value)
+			classesData[desc.ClassName] = classData
+		}
 		for _, f := range desc.Info.Fields {
+			if d.handler != nil {
+				if err := d.handler.Field(desc, f.FieldName, f.TypeCode); err != nil {
+					return err
+				}
+			}
+			d.pushField(desc.ClassName, f.FieldName)
 			v, err := d.readValue(f.TypeCode)
+			d.popPath()
 			if err != nil && !errors.Is(err, ErrNotSupported) {
-				return nil, fmt.Errorf("d.readValue: %w", err)
+				if d.opts.recover && isPartial(v) && !d.skipping {
+					classData[f.FieldName] = v
+				}
+				return fmt.Errorf("d.readValue: %w", err)
+			}
+			if !d.skipping {
+				classData[f.FieldName] = v
 			}
-			classData[f.FieldName] = v
 		}
 		if desc.Info.Flags.IsSerializable() && desc.Info.Flags.HasWriteMethod() {
+			d.pushField(desc.ClassName, objectAnnotationKey)
 			contents, err := d.readAnnotation()
+			d.popPath()
 			if err != nil {
-				return nil, fmt.Errorf("d.readAnnotation: %w", err)
+				if d.opts.recover && len(contents) > 0 && !d.skipping {
+					classData[objectAnnotationKey] = contents
+				}
+				return fmt.Errorf("d.readAnnotation: %w", err)
 			}
-			if len(contents) > 0 {
-				classData["[object annotation]"] = contents
+			if len(contents) > 0 && !d.skipping {
+				classData[objectAnnotationKey] = contents
 			}
 		}
-		classesData[desc.ClassName] = classData
 	}
 
-	return classesData, nil
+	return nil
 }
 
-func (d *Decoder) readValue(t TypeCode) (Value, error) {
+func (d *Decoder) readValue(t TypeCode) (v Value, err error) {
+	if d.opts.recover {
+		defer func() { d.noteError(err) }()
+	}
+	if t.IsPrimitive() && d.opts.recordSpans {
+		defer d.closeSpan(d.openSpan(SpanValue, d.r.n))
+	}
+	if t.IsPrimitive() && d.handler != nil {
+		defer func() {
+			if err == nil {
+				err = d.handler.Primitive(v)
+			}
+		}()
+	}
 	switch t {
 	case TypeByte:
 		return d.r.readInt8()
@@ -440,34 +981,69 @@ func (d *Decoder) readValue(t TypeCode) (Value, error) {
 // endBlockData:
 //   TC_ENDBLOCKDATA
 
-func (d *Decoder) readBlockDataShort() (blockData, error) {
+func (d *Decoder) readBlockDataShort() (BlockData, error) {
 	// blockdatashort:
 	//   TC_BLOCKDATA (unsigned byte)<size> (byte)[size]
 	l, err := d.r.readUint8()
 	if err != nil {
 		return nil, fmt.Errorf("readUint8: %w", err)
 	}
-	b, err := d.r.readBytes(int64(l))
+	b, err := d.readBlock(int64(l))
 	if err != nil {
-		return nil, fmt.Errorf("readBytes: %w", err)
+		return nil, fmt.Errorf("d.readBlock: %w", err)
 	}
 	return b, nil
 }
 
-func (d *Decoder) readBlockDataLong() (blockData, error) {
+func (d *Decoder) readBlockDataLong() (BlockData, error) {
 	// blockdatalong:
 	//   TC_BLOCKDATALONG (int)<size> (byte)[size]
 	l, err := d.r.readInt32()
 	if err != nil {
 		return nil, fmt.Errorf("readInt32: %w", err)
 	}
-	b, err := d.r.readBytes(int64(l))
+	b, err := d.readBlock(int64(l))
+	if err != nil {
+		return nil, fmt.Errorf("d.readBlock: %w", err)
+	}
+	return b, nil
+}
+
+// readBlock reads the contents of a block data record and reports them to the
+// handler, if any. They share the input's memory when decoding with
+// WithZeroCopy.
+func (d *Decoder) readBlock(count int64) (BlockData, error) {
+	if d.skipping && d.handler == nil {
+		return BlockData{}, d.skipBytes(count)
+	}
+	var b []byte
+	var err error
+	if d.zeroCopy() || d.skipping {
+		b, err = d.r.readView(count)
+	} else {
+		b, err = d.r.readBytes(count)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("readBytes: %w", err)
+		return nil, err
+	}
+	if d.handler != nil {
+		if err := d.handler.BlockData(b); err != nil {
+			return nil, err
+		}
+	}
+	if d.skipping {
+		return BlockData{}, nil
 	}
 	return b, nil
 }
 
+func (d *Decoder) skipBytes(count int64) error {
+	if err := d.r.skip(count); err != nil {
+		return fmt.Errorf("skip: %w", err)
+	}
+	return nil
+}
+
 func (d *Decoder) readNewString() (string, error) {
 	// newString:
 	//   TC_STRING newHandle (utf)
@@ -478,6 +1054,9 @@ func (d *Decoder) readNewString() (string, error) {
 		return "", fmt.Errorf("d.readString: %w", err)
 	}
 	d.o[h] = s
+	if d.handler != nil {
+		return s, d.handler.String(s, int(h))
+	}
 	return s, nil
 }
 
@@ -490,12 +1069,20 @@ func (d *Decoder) readNewLongString() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("readInt64: %w", err)
 	}
-	s, err := d.r.readBytes(l)
+	if d.skipping && d.handler == nil {
+		d.o[h] = ""
+		return "", d.skipBytes(l)
+	}
+	b, err := d.r.readView(l)
 	if err != nil {
-		return "", fmt.Errorf("readBytes: %w", err)
+		return "", fmt.Errorf("readView: %w", err)
 	}
-	d.o[h] = string(s)
-	return string(s), nil
+	s := string(b)
+	d.o[h] = s
+	if d.handler != nil {
+		return s, d.handler.String(s, int(h))
+	}
+	return s, nil
 }
 
 func (d *Decoder) readNewEnum() (Enum, error) {
@@ -505,12 +1092,16 @@ func (d *Decoder) readNewEnum() (Enum, error) {
 	//   (String)object
 	var e Enum
 	var err error
-	e.ClassDesc, err = d.readClassDesc()
+	e.ClassDesc, err = d.readOwnClassDesc()
 	if err != nil {
 		return e, fmt.Errorf("d.readClassDesc: %w", err)
 	}
 	h := d.newHandle()
+	d.pushPath("[constant]")
+	unmute := d.muteHandler()
 	c, err := d.readContent()
+	unmute()
+	d.popPath()
 	if err != nil {
 		return e, fmt.Errorf("d.readContent: %w", err)
 	}
@@ -519,26 +1110,39 @@ func (d *Decoder) readNewEnum() (Enum, error) {
 		return e, fmt.Errorf("failed to cast reference to string")
 	}
 	d.o[h] = e
+	if d.handler != nil {
+		return e, d.handler.Enum(e.ClassDesc, int(h), e.ConstantName)
+	}
 	return e, nil
 }
 
 func (d *Decoder) newHandle() handle {
 	h := d.h
 	d.h++
+	if d.opts.recordSpans {
+		d.setSpanHandle(h)
+	}
 	return h
 }
 
 func (d *Decoder) readAnnotation() ([]Annotation, error) {
 	var annotations []Annotation
-	for {
+	for i := 0; ; i++ {
+		d.pushIndex(i)
 		content, err := d.readContent()
+		d.popPath()
 		if err != nil {
-			return nil, err
+			if d.opts.recover && isPartial(content) && !d.skipping {
+				annotations = append(annotations, content)
+			}
+			return annotations, err
 		}
 		if content == nil {
 			break
 		}
-		annotations = append(annotations, content)
+		if !d.skipping {
+			annotations = append(annotations, content)
+		}
 	}
 	return annotations, nil
 }
@@ -550,7 +1154,16 @@ func (d *Decoder) readReference() (Content, error) {
 	if err != nil {
 		return nil, fmt.Errorf("readInt32: %w", err)
 	}
-	return d.o[handle(h)], err
+	if d.opts.recordSpans {
+		d.setSpanHandle(handle(h))
+	}
+	if _, ok := d.reading[handle(h)]; ok {
+		d.reading[handle(h)] = true
+	}
+	if d.handler != nil {
+		return d.o[handle(h)], d.handler.Reference(int(h))
+	}
+	return d.o[handle(h)], nil
 }
 
 func (d *Decoder) readException() (Object, error) {
@@ -583,18 +1196,21 @@ func (d *Decoder) readString() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("readUint16: %w", err)
 	}
-	s, err := d.r.readBytes(int64(l))
+	s, err := d.r.readView(int64(l))
 	if err != nil {
-		return "", fmt.Errorf("readBytes: %w", err)
+		return "", fmt.Errorf("readView: %w", err)
 	}
 	return string(s), nil
 }
 
-type blockData []byte
 type handle int
 
 const baseHandleValue = 0x7e0000
 
+// objectAnnotationKey is the pseudo-field under which ClassData stores the
+// contents written by a class's writeObject method.
+const objectAnnotationKey = "[object annotation]"
+
 const (
 	tcNull           TypeCode = 0x70
 	tcReference      TypeCode = 0x71
@@ -1,9 +1,15 @@
 package java
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/hex"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -16,7 +22,15 @@ func TestDeserializer_ReadContents(t *testing.T) {
 		expected []Content
 	}{
 		{"ArrayList", []Content{al}},
-		{"bytes", []Content{Array{ClassDesc: &byteArray, Values: byteArrayValue("11223344")}}},
+		{"bytes", []Content{Array{ClassDesc: &byteArray, Data: byteArrayData("11223344")}}},
+		{"chars", []Content{Array{ClassDesc: &charArray, Data: []uint16{'h', 0xe9, 'l', 'l', 'o', ' ', 0xd83d, 0xde00}}}},
+		{"ints", []Content{Array{ClassDesc: &intArray, Data: []int32{1, -2, math.MaxInt32}}}},
+		{"jagged", []Content{Array{ClassDesc: &intArray2, Values: []Value{
+			Array{ClassDesc: &intArray, Data: []int32{1, 2}},
+			Array{ClassDesc: &intArray, Data: []int32{3}},
+			nil,
+			Array{ClassDesc: &intArray, Data: []int32{}},
+		}}}},
 		{"enum", []Content{Enum{ClassDesc: &comEdutkoMainStatus, ConstantName: "FUBAR"}}},
 		{"int", []Content{Object{
 			ClassDesc: &javaLangInteger,
@@ -46,16 +60,409 @@ func TestDeserializer_ReadContents(t *testing.T) {
 	}
 }
 
-func byteArrayValue(s string) []Value {
-	bs, err := hex.DecodeString(s)
+func TestDecoder_Decode_cycle(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "Node.ser"))
 	if err != nil {
 		panic(err)
 	}
-	var vs []Value
-	for _, b := range bs {
-		vs = append(vs, Value(int8(b)))
+	defer f.Close()
+
+	c, err := NewDecoder(f).Decode()
+	assert.Nil(t, err)
+
+	const node = "com.edutko.Main$Node"
+	root := c.(Object)
+	a := root.ClassData[node]["next"].(Object)
+	b := a.ClassData[node]["next"].(Object)
+	assert.Equal(t, "b", b.ClassData[node]["name"])
+	assert.Nil(t, root.ClassData[node]["parent"])
+	for _, child := range []Object{a, b} {
+		parent := child.ClassData[node]["parent"].(Object)
+		assert.Equal(t, root.ClassDesc, parent.ClassDesc)
+		assert.Equal(t, reflect.ValueOf(root.ClassData).Pointer(), reflect.ValueOf(parent.ClassData).Pointer())
+	}
+}
+
+func TestDecoder_Decode_selfReferencingArray(t *testing.T) {
+	// An Object[] whose first element is the array itself.
+	c, err := NewBytesDecoder(mustReadFile("self-array")).Decode()
+	assert.Nil(t, err)
+
+	a := c.(Array)
+	assert.Equal(t, "[Ljava.lang.Object;", a.ClassDesc.ClassName)
+	assert.Len(t, a.Values, 2)
+	assert.Equal(t, "hi", a.Values[1])
+	self := a.Values[0].(Array)
+	assert.Equal(t, a.ClassDesc, self.ClassDesc)
+	assert.Equal(t, a.Values, self.Values[:cap(self.Values)], "the reference shares the array's values")
+	assert.Same(t, &a.Values[0], &self.Values[:1][0])
+}
+
+func TestDecoder_Decode_longSelfReferencingArray(t *testing.T) {
+	// An Object[] longer than the values preallocated for it, whose first and
+	// last elements are the array itself.
+	const n = 3 * maxArrayPrealloc
+	data := append([]byte{}, mustReadFile("self-array")[:40]...)
+	data = binary.BigEndian.AppendUint32(data, n)
+	data = append(data, 0x71, 0x00, 0x7e, 0x00, 0x01)
+	data = append(data, bytes.Repeat([]byte{0x70}, n-2)...)
+	data = append(data, 0x71, 0x00, 0x7e, 0x00, 0x01)
+
+	c, err := NewBytesDecoder(data).Decode()
+	assert.Nil(t, err)
+	a := c.(Array)
+	assert.Len(t, a.Values, n)
+	for _, i := range []int{0, n - 1} {
+		self := a.Values[i].(Array)
+		assert.Len(t, self.Values, n)
+		assert.Same(t, &a.Values[0], &self.Values[0])
+	}
+}
+
+func TestDecoder_Decode_unparsableArrayClass(t *testing.T) {
+	// The array class of a hidden class, whose name contains a slash.
+	name := "[Lcom.acme.Foo/0x0000000800c01000;"
+	data := unhex("aced00057572")
+	data = append(data, 0x00, byte(len(name)))
+	data = append(data, name...)
+	data = append(data, unhex("0000000000000001020000787000000001740002")...)
+	data = append(data, "hi"...)
+
+	c, err := NewBytesDecoder(data).Decode()
+	assert.Nil(t, err)
+	a := c.(Array)
+	assert.Equal(t, name, a.ClassDesc.ClassName)
+	assert.Equal(t, []Value{"hi"}, a.Values)
+}
+
+func TestDecoder_Decode_concatenatedStreams(t *testing.T) {
+	// Each stream starts with its own header, as when a new ObjectOutputStream
+	// is used for each element.
+	data := append(mustReadFile("string"), mustReadFile("enum")...)
+	expected := []Content{"hi", Enum{ClassDesc: &comEdutkoMainStatus, ConstantName: "FUBAR"}}
+
+	d := NewDecoder(bytes.NewReader(data))
+	for _, e := range expected {
+		c, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, e, c)
+	}
+	_, err := d.Decode()
+	assert.ErrorIs(t, err, io.EOF)
+
+	contents, err := NewBytesDecoder(data).DecodeAll(-1)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, expected, contents)
+
+	d = NewDecoder(bytes.NewReader(data))
+	n, err := d.Skip()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(mustReadFile("string"))), n)
+	n, err = d.Skip()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(mustReadFile("enum"))), n)
+}
+
+func TestDecoder_Decode_concatenatedStreamsWithReferences(t *testing.T) {
+	// Both streams use handles from 0x7e0000, and the second refers back to
+	// its own class descriptors.
+	data := append(mustReadFile("Node"), mustReadFile("objects")...)
+	expected := Array{ClassDesc: &comEdutkoMainFooArray, Values: []Value{foo1, foo2, foo3}}
+
+	d := NewDecoder(bytes.NewReader(data))
+	_, err := d.Decode()
+	assert.Nil(t, err)
+	c, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, c)
+
+	contents, err := NewBytesDecoder(data).DecodeAll(-1)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Len(t, contents, 2)
+	assert.Equal(t, expected, contents[1])
+
+	f := NewFeedDecoder()
+	defer f.Close()
+	contents, err = f.Feed(data)
+	assert.Nil(t, err)
+	assert.Len(t, contents, 2)
+	assert.Equal(t, expected, contents[1])
+}
+
+func TestDecoder_Skip(t *testing.T) {
+	for _, name := range []string{"ArrayList", "bytes", "chars", "enum", "jagged", "long-string", "Node", "object", "objects", "SealedObjectForKeyProtector", "self-array", "strings"} {
+		t.Run(name, func(t *testing.T) {
+			data := mustReadFile(name)
+			d := NewDecoder(bytes.NewReader(data))
+			_, err := d.Decode()
+			assert.Nil(t, err)
+
+			n, err := NewDecoder(bytes.NewReader(data)).Skip()
+			assert.Nil(t, err)
+			assert.Equal(t, d.r.n, n)
+			if name != "SealedObjectForKeyProtector" {
+				assert.Equal(t, int64(len(data)), n)
+			}
+		})
+	}
+
+	t.Run("handles", func(t *testing.T) {
+		data := mustReadFile("Node")
+		// A reference to node "a", followed by a new node using the skipped
+		// class descriptor, with a reference to the skipped string "b" as its
+		// parent.
+		data = append(data, 0x71, 0x00, 0x7e, 0x00, 0x05)
+		data = append(data, 0x73, 0x71, 0x00, 0x7e, 0x00, 0x00, 0x74, 0x00, 0x01, 'c', 0x70, 0x71, 0x00, 0x7e, 0x00, 0x08)
+		d := NewDecoder(bytes.NewReader(data))
+
+		n, err := d.Skip()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(mustReadFile("Node"))), n)
+
+		c, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, "com.edutko.Main$Node", c.(Object).GetClassName())
+		assert.Nil(t, c.(Object).ClassData)
+
+		c, err = d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, Object{
+			ClassDesc: c.(Object).ClassDesc,
+			ClassData: ClassData{"com.edutko.Main$Node": {"name": "c", "next": nil, "parent": "b"}},
+		}, c)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		data := mustReadFile("long-string")
+		n, err := NewDecoder(bytes.NewReader(data[:100])).Skip()
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, int64(100), n)
+	})
+}
+
+func TestDecoder_InputOffset(t *testing.T) {
+	t.Run("trailing data", func(t *testing.T) {
+		data := mustReadFile("SealedObjectForKeyProtector")
+		r := bytes.NewReader(data)
+		d := NewDecoder(r)
+		assert.Equal(t, int64(0), d.InputOffset())
+
+		_, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(465), d.InputOffset())
+		assert.Equal(t, len(data)-465, r.Len(), "the decoder must not read past the object")
+	})
+
+	t.Run("multiple elements", func(t *testing.T) {
+		header := len(StreamMagic) + 2
+		str, enum := mustReadFile("string"), mustReadFile("enum")
+		data := append(append([]byte{}, str...), enum[header:]...)
+		data = append(data, "trailer"...)
+		r := bytes.NewReader(data)
+		d := NewDecoder(r)
+
+		_, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(str)), d.InputOffset())
+
+		_, err = d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(str)+len(enum)-header), d.InputOffset())
+
+		rest, _ := io.ReadAll(r)
+		assert.Equal(t, "trailer", string(rest))
+	})
+
+	t.Run("unseekable reader", func(t *testing.T) {
+		data := mustReadFile("SealedObjectForKeyProtector")
+		r := bytes.NewReader(data)
+		d := NewDecoder(struct{ io.Reader }{r})
+
+		n, err := d.Skip()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(465), n)
+		assert.Equal(t, len(data)-465, r.Len(), "the decoder must not read past the object")
+	})
+}
+
+func TestNewBytesDecoder(t *testing.T) {
+	t.Run("same as NewDecoder", func(t *testing.T) {
+		for _, name := range []string{"ArrayList", "Node", "ints", "jagged", "long-string", "object", "SealedObjectForKeyProtector"} {
+			data := mustReadFile(name)
+			nd := NewDecoder(bytes.NewReader(data))
+			expected, err := nd.Decode()
+			assert.Nil(t, err)
+
+			d := NewBytesDecoder(data)
+			actual, err := d.Decode()
+			assert.Nil(t, err)
+			assert.Equal(t, expected, actual, name)
+			assert.Equal(t, nd.InputOffset(), d.InputOffset(), name)
+		}
+	})
+
+	t.Run("zero copy", func(t *testing.T) {
+		data := mustReadFile("bytes")
+		c, err := NewBytesDecoder(data, WithZeroCopy()).Decode()
+		assert.Nil(t, err)
+		b, ok := c.(Array).Bytes()
+		assert.True(t, ok)
+		assert.Equal(t, byteArrayData("11223344"), b)
+		assert.Same(t, &data[len(data)-4], &b[0])
+
+		data = mustReadFile("ints")
+		c, err = NewBytesDecoder(data, WithZeroCopy()).Decode()
+		assert.Nil(t, err)
+		arr := c.(Array)
+		assert.Nil(t, arr.Data)
+		assert.Same(t, &data[len(data)-12], &arr.Raw[0])
+		assert.Equal(t, TypeInteger, arr.rawType)
+		assert.Equal(t, 3, arr.Length())
+		assert.Equal(t, -2, arr.Get(1))
+		ints, ok := arr.Ints()
+		assert.True(t, ok)
+		assert.Equal(t, []int32{1, -2, math.MaxInt32}, ints)
+
+		data = mustReadFile("ArrayList")
+		c, err = NewBytesDecoder(data, WithZeroCopy()).Decode()
+		assert.Nil(t, err)
+		block := c.(Object).GetAnnotation("java.util.ArrayList")[0].(BlockData)
+		assert.Same(t, &data[53], &block[0])
+
+		var v []int32
+		assert.Nil(t, UnmarshalWithOptions(mustReadFile("ints"), &v, WithZeroCopy()))
+		assert.Equal(t, []int32{1, -2, math.MaxInt32}, v)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		data := mustReadFile("object")
+		_, err := NewBytesDecoder(data[:len(data)-1], WithZeroCopy()).Decode()
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		_, err = NewBytesDecoder(data[:4]).Decode()
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestNewReaderAtDecoder(t *testing.T) {
+	t.Run("same as NewDecoder", func(t *testing.T) {
+		for _, name := range []string{"ArrayList", "Node", "ints", "jagged", "long-string", "object", "SealedObjectForKeyProtector"} {
+			data := mustReadFile(name)
+			nd := NewDecoder(bytes.NewReader(data))
+			expected, err := nd.Decode()
+			assert.Nil(t, err)
+
+			padded := append([]byte("leading"), data...)
+			d := NewReaderAtDecoder(bytes.NewReader(padded), 7, int64(len(data)))
+			actual, err := d.Decode()
+			assert.Nil(t, err)
+			assert.Equal(t, expected, actual, name)
+			assert.Equal(t, nd.InputOffset(), d.InputOffset(), name)
+		}
+	})
+
+	t.Run("zero copy", func(t *testing.T) {
+		// A byte array larger than any window, between two int arrays.
+		large := mustReadFile("bytes")
+		large = binary.BigEndian.AppendUint32(large[:len(large)-8:len(large)-8], 3*windowSize)
+		large = append(large, bytes.Repeat([]byte{0x5a}, 3*windowSize)...)
+		var data []byte
+		data = append(data, mustReadFile("ints")...)
+		data = append(data, large...)
+		data = append(data, mustReadFile("ints")...)
+
+		d := NewReaderAtDecoder(bytes.NewReader(data), 0, int64(len(data)), WithZeroCopy())
+		c, err := d.DecodeAll(3)
+		assert.Nil(t, err)
+		first := c[0].(Array)
+		assert.Nil(t, first.Data)
+		assert.NotNil(t, first.Raw)
+		b, ok := c[1].(Array).Bytes()
+		assert.True(t, ok)
+		assert.Equal(t, bytes.Repeat([]byte{0x5a}, 3*windowSize), b)
+
+		// Earlier views are not overwritten by later reads.
+		ints, ok := first.Ints()
+		assert.True(t, ok)
+		assert.Equal(t, []int32{1, -2, math.MaxInt32}, ints)
+		ints, ok = c[2].(Array).Ints()
+		assert.True(t, ok)
+		assert.Equal(t, []int32{1, -2, math.MaxInt32}, ints)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		data := mustReadFile("bytes")
+		data = binary.BigEndian.AppendUint32(data[:len(data)-8:len(data)-8], math.MaxInt32)
+		data = append(data, 0x11)
+		_, err := NewReaderAtDecoder(bytes.NewReader(data), 0, int64(len(data)), WithZeroCopy()).Decode()
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+		data = mustReadFile("object")
+		_, err = NewReaderAtDecoder(bytes.NewReader(data), 0, int64(len(data)-1)).Decode()
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
+
+func TestDecoder_DecodeContext(t *testing.T) {
+	t.Run("not canceled", func(t *testing.T) {
+		d := NewDecoder(bytes.NewReader(mustReadFile("objects")))
+		c, err := d.DecodeContext(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, Array{ClassDesc: &comEdutkoMainFooArray, Values: []Value{foo1, foo2, foo3}}, c)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		d := NewDecoder(bytes.NewReader(mustReadFile("objects")))
+		_, err := d.DecodeContext(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = d.DecodeContext(context.Background())
+		assert.Nil(t, err, "the context only applies to one call")
+	})
+
+	t.Run("canceled while decoding", func(t *testing.T) {
+		for _, checks := range []int{2, 3, 10} {
+			data := mustReadFile("objects")
+			d := NewDecoder(bytes.NewReader(data), WithRecovery())
+			_, err := d.DecodeContext(&countdownContext{Context: context.Background(), n: checks})
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Less(t, d.InputOffset(), int64(len(data)))
+			assert.Nil(t, d.Diagnostics(), "cancellation is not recovered from")
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 0)
+		defer cancel()
+		var v []int32
+		err := UnmarshalContext(ctx, mustReadFile("ints"), &v)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		err = NewDecoder(bytes.NewReader(mustReadFile("ints"))).UnmarshalContext(ctx, &v)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+// countdownContext is canceled once its Err method has been called n times.
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (c *countdownContext) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func byteArrayData(s string) []byte {
+	bs, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
 	}
-	return vs
+	return bs
 }
 
 var comEdutkoMainFoo = Class{
@@ -126,7 +533,7 @@ var al = Object{
 		"java.util.ArrayList": {
 			"size": 4,
 			"[object annotation]": []Annotation{
-				blockData{0x00, 0x00, 0x00, 0x04},
+				BlockData{0x00, 0x00, 0x00, 0x04},
 				javaInteger(0xaaaaaa),
 				javaInteger(0xbbbbbb),
 				javaInteger(0xcccccc),
@@ -151,7 +558,7 @@ var foo1 = Object{
 			"o":    "hello",
 			"a": Array{
 				ClassDesc: &byteArray,
-				Values:    byteArrayValue("112233"),
+				Data:      byteArrayData("112233"),
 			},
 			"prefix": Enum{
 				ClassDesc:    &comEdutkoMainPrefix,
@@ -197,7 +604,7 @@ var foo2 = Object{
 			"o":    "hola",
 			"a": Array{
 				ClassDesc: &byteArray,
-				Values:    byteArrayValue("445566"),
+				Data:      byteArrayData("445566"),
 			},
 			"prefix": Enum{
 				ClassDesc:    &comEdutkoMainPrefix,
@@ -243,7 +650,7 @@ var foo3 = Object{
 			"o":    "aloha",
 			"a": Array{
 				ClassDesc: &byteArray,
-				Values:    byteArrayValue("777777"),
+				Data:      byteArrayData("777777"),
 			},
 			"prefix": Enum{
 				ClassDesc:    &comEdutkoMainPrefix,
@@ -274,11 +681,11 @@ var secretKey = Object{
 		"javax.crypto.SealedObject": {
 			"encodedParams": Array{
 				ClassDesc: &byteArray,
-				Values:    byteArrayValue("300f0408a47298a326aba6500203030d40"),
+				Data:      byteArrayData("300f0408a47298a326aba6500203030d40"),
 			},
 			"encryptedContent": Array{
 				ClassDesc: &byteArray,
-				Values:    byteArrayValue("dde157cfd1670f6e76efb93953cfdd4737bc24f9098253e8defb40a8e3e428efd025224f3ad8dba5e71bd16eccfa429a21fbcaa4cb8b5050866014fbaf4be4c3cbfe77aecf4438437b054da882b73e766020f83628e5d85d0fd03b5cc36d8ca8b294aa92afb8efdf3d55fb57683b0cd80c43308ce63552c49c05824980ae1e0122bddcb4f4016e9f324b88c535f90fa2ac2e53119a38721d6012af87e7f40522"),
+				Data:      byteArrayData("dde157cfd1670f6e76efb93953cfdd4737bc24f9098253e8defb40a8e3e428efd025224f3ad8dba5e71bd16eccfa429a21fbcaa4cb8b5050866014fbaf4be4c3cbfe77aecf4438437b054da882b73e766020f83628e5d85d0fd03b5cc36d8ca8b294aa92afb8efdf3d55fb57683b0cd80c43308ce63552c49c05824980ae1e0122bddcb4f4016e9f324b88c535f90fa2ac2e53119a38721d6012af87e7f40522"),
 			},
 			"paramsAlg": "PBEWithMD5AndTripleDES",
 			"sealAlg":   "PBEWithMD5AndTripleDES",
@@ -2,12 +2,15 @@ package java
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 )
 
 var ErrNotSupported = errors.New("not supported")
 var ErrNoSuchClass = errors.New("no such class in object")
 var ErrNoSuchField = errors.New("no such field in object")
+var ErrUnmappedField = errors.New("field not mapped to Go value")
+var ErrClassMismatch = errors.New("object is not an instance of the declared class")
 
 // An InvalidUnmarshalError describes an invalid argument passed to Unmarshal.
 // (The argument to Unmarshal must be a non-nil pointer.)
@@ -25,3 +28,62 @@ func (e *InvalidUnmarshalError) Error() string {
 	}
 	return "json: Unmarshal(nil " + e.Type.String() + ")"
 }
+
+// An IncompatibleClassError describes serialized data whose serialVersionUID
+// is not accepted by the Go type it is being unmarshalled into.
+type IncompatibleClassError struct {
+	ClassName        string
+	SerialVersionUID SerialVersionUID
+	Accepted         []SerialVersionUID
+	Type             reflect.Type
+}
+
+func (e *IncompatibleClassError) Error() string {
+	return fmt.Sprintf("%s: incompatible class: stream serialVersionUID = %d, %s accepts %v",
+		e.ClassName, e.SerialVersionUID, e.Type, e.Accepted)
+}
+
+// An UnmarshalTypeError describes a Java value that cannot be stored in the
+// Go value it maps to.
+type UnmarshalTypeError struct {
+	JavaPath string       // location in the Java object graph, e.g. "com.acme.Foo.bars[1]/com.acme.Bar.value"
+	GoPath   string       // location in the Go value, e.g. "Bars[1].Value"
+	JavaType string       // Java type of the value, e.g. "int" or "java.lang.String"
+	GoType   reflect.Type // type of the Go value
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	msg := "cannot cast " + e.JavaType + " to " + e.GoType.String()
+	if e.JavaPath != "" {
+		msg += " at " + e.JavaPath
+	}
+	if e.GoPath != "" {
+		msg += " (Go field " + e.GoPath + ")"
+	}
+	return msg
+}
+
+// An UnmarshalError describes an error that occurred while unmarshalling the
+// Java value at JavaPath into the Go value at GoPath. Errors other than
+// UnmarshalTypeError are wrapped in an UnmarshalError unless they occur at the
+// top level.
+type UnmarshalError struct {
+	JavaPath string // as in UnmarshalTypeError
+	GoPath   string // as in UnmarshalTypeError
+	Err      error
+}
+
+func (e *UnmarshalError) Error() string {
+	msg := e.Err.Error()
+	if e.JavaPath != "" {
+		msg += " at " + e.JavaPath
+	}
+	if e.GoPath != "" {
+		msg += " (Go field " + e.GoPath + ")"
+	}
+	return msg
+}
+
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,201 @@
+package java
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNeedMoreData is returned by FeedDecoder.Feed when the data fed so far
+// does not complete another element.
+var ErrNeedMoreData = errors.New("need more data")
+
+var errFeedClosed = errors.New("feed decoder is closed")
+
+// A FeedDecoder decodes a stream that arrives in chunks, for callers that
+// cannot block waiting for input. Elements are decoded as by Decoder, sharing
+// one handle table.
+//
+// An element that has only been partly fed is decoded as far as the input
+// goes by a goroutine that then waits for the rest of it, so each byte is
+// decoded once however small the chunks are. The goroutine stops when the
+// input ends between two elements or decoding fails; otherwise, Close stops
+// it, so callers that may abandon a FeedDecoder should defer a call to Close.
+type FeedDecoder struct {
+	d *Decoder
+	// in passes fed data to the goroutine that decodes a partly fed element,
+	// and out passes back what it decodes. The two take turns, so the
+	// decoder is only used by one of them at a time.
+	in      chan []byte
+	out     chan feedResult
+	wg      sync.WaitGroup
+	running bool
+	closed  bool
+	err     error
+
+	// offset, spans and diagnostics are the decoder's counts as of the end
+	// of the last element decoded.
+	offset      int64
+	spans       int
+	diagnostics int
+}
+
+// A feedResult is sent by the decoding goroutine for each element it decodes,
+// and with ErrNeedMoreData once it has used up the input. exited is set if
+// the goroutine returned rather than waiting for more.
+type feedResult struct {
+	c      Content
+	err    error
+	exited bool
+}
+
+// NewFeedDecoder returns a decoder for input passed to Feed.
+func NewFeedDecoder(opts ...Option) *FeedDecoder {
+	f := &FeedDecoder{
+		d:   newBytesDecoder(nil, newOptions(opts)),
+		in:  make(chan []byte),
+		out: make(chan feedResult),
+	}
+	f.d.r.more = f.more
+	return f
+}
+
+// Feed adds data to the input and returns the top-level elements that it
+// completes, except for nulls and resets, as DecodeAll would. The remainder of
+// the input is kept for the next call. Feed returns ErrNeedMoreData if there
+// are no elements to return; other errors leave the decoder unusable. data is
+// copied, so the caller may reuse it.
+func (f *FeedDecoder) Feed(data []byte) ([]Content, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.running {
+		f.in <- data
+	} else {
+		f.d.r.append(data)
+		if f.d.r.buffered() == 0 {
+			return nil, ErrNeedMoreData
+		}
+		f.running = true
+		f.wg.Add(1)
+		go f.run()
+	}
+
+	var contents []Content
+	for {
+		res := <-f.out
+		if res.exited {
+			f.running = false
+			f.wg.Wait()
+		}
+		if errors.Is(res.err, ErrNeedMoreData) {
+			break
+		}
+		if res.err != nil {
+			f.err = res.err
+			return contents, res.err
+		}
+		if res.c != nil {
+			contents = append(contents, res.c)
+		}
+	}
+	if len(contents) == 0 {
+		return nil, ErrNeedMoreData
+	}
+	return contents, nil
+}
+
+// Close stops decoding the element that has been partly fed, if any, and waits
+// for the goroutine decoding it to return. Feed returns an error once the
+// decoder is closed.
+func (f *FeedDecoder) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	if f.running {
+		close(f.in)
+		f.running = false
+		f.wg.Wait()
+	}
+	if f.err == nil {
+		f.err = errFeedClosed
+	}
+	return nil
+}
+
+// Buffered returns the number of bytes fed but not yet decoded as part of a
+// complete element.
+func (f *FeedDecoder) Buffered() int {
+	return int(f.d.r.n + int64(f.d.r.buffered()) - f.offset)
+}
+
+// InputOffset returns the offset of the end of the last element decoded.
+func (f *FeedDecoder) InputOffset() int64 {
+	return f.offset
+}
+
+// Diagnostics is like Decoder.Diagnostics, for the elements decoded so far.
+func (f *FeedDecoder) Diagnostics() []Diagnostic {
+	return f.d.Diagnostics()[:f.diagnostics]
+}
+
+// Spans is like Decoder.Spans, for the elements decoded so far.
+func (f *FeedDecoder) Spans() []Span {
+	return f.d.Spans()[:f.spans]
+}
+
+// run decodes elements until the input runs out between two of them.
+func (f *FeedDecoder) run() {
+	defer f.wg.Done()
+	d := f.d
+	for {
+		c, err := f.next()
+		if f.closed {
+			return
+		}
+		if err != nil {
+			f.out <- feedResult{err: err, exited: true}
+			return
+		}
+		f.offset, f.spans, f.diagnostics = d.r.n, len(d.spans), len(d.diagnostics)
+		f.out <- feedResult{c: c}
+		if d.r.buffered() == 0 {
+			f.out <- feedResult{err: ErrNeedMoreData, exited: true}
+			return
+		}
+	}
+}
+
+// next decodes the next top-level element.
+func (f *FeedDecoder) next() (Content, error) {
+	d := f.d
+	if err := d.readStreamHeader(); err != nil {
+		return nil, err
+	}
+	start := d.r.n
+	c, err := d.readTopLevelContent()
+	if err != nil && d.opts.recover && d.r.n > start {
+		if !isPartial(c) {
+			c = nil
+		}
+		d.recoverFrom(err)
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("d.readContent: %w", err)
+	}
+	return c, nil
+}
+
+// more is called by the decoder when it has used up the input. It hands
+// control back to Feed and waits for more data, which it returns. It returns
+// false once the decoder is closed.
+func (f *FeedDecoder) more() ([]byte, bool) {
+	if f.closed {
+		return nil, false
+	}
+	f.out <- feedResult{err: ErrNeedMoreData}
+	data, ok := <-f.in
+	return data, ok
+}
@@ -0,0 +1,161 @@
+package java
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeedDecoder_Feed(t *testing.T) {
+	t.Run("byte by byte", func(t *testing.T) {
+		data := mustReadFile("object")
+		f := NewFeedDecoder()
+		defer f.Close()
+		for i := 0; i < len(data)-1; i++ {
+			c, err := f.Feed(data[i : i+1])
+			assert.ErrorIs(t, err, ErrNeedMoreData)
+			assert.Nil(t, c)
+		}
+		c, err := f.Feed(data[len(data)-1:])
+		assert.Nil(t, err)
+		assert.Equal(t, []Content{foo1}, c)
+		assert.Equal(t, int64(len(data)), f.InputOffset())
+		assert.Zero(t, f.Buffered())
+	})
+
+	t.Run("shared handles", func(t *testing.T) {
+		// Node.ser followed by a reference to the root node.
+		data := append(mustReadFile("Node"), byte(tcReference), 0x00, 0x7e, 0x00, 0x03)
+		data = append(data, mustReadFile("string")[4:]...)
+
+		f := NewFeedDecoder()
+		defer f.Close()
+		var contents []Content
+		for i := 0; i < len(data); i += 7 {
+			end := i + 7
+			if end > len(data) {
+				end = len(data)
+			}
+			c, err := f.Feed(data[i:end])
+			if err != ErrNeedMoreData {
+				assert.Nil(t, err)
+			}
+			contents = append(contents, c...)
+		}
+
+		expected, err := NewDecoder(bytes.NewReader(data)).DecodeAll(3)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, contents)
+		assert.Len(t, contents, 3)
+		assert.Equal(t, contents[0], contents[1])
+	})
+
+	t.Run("several elements per chunk", func(t *testing.T) {
+		str := mustReadFile("string")
+		data := append(append([]byte{}, str...), str[4:]...)
+		data = append(data, mustReadFile("enum")[4:10]...)
+
+		f := NewFeedDecoder()
+		defer f.Close()
+		c, err := f.Feed(data)
+		assert.Nil(t, err)
+		assert.Len(t, c, 2)
+		assert.Equal(t, 6, f.Buffered())
+	})
+
+	t.Run("large element", func(t *testing.T) {
+		data := largeIntArray(1000)
+		f := NewFeedDecoder()
+		defer f.Close()
+		_, err := f.Feed(data[:100])
+		assert.ErrorIs(t, err, ErrNeedMoreData)
+		assert.Equal(t, 100, f.Buffered())
+		assert.Zero(t, f.InputOffset())
+
+		_, err = f.Feed(data[100 : len(data)-1])
+		assert.ErrorIs(t, err, ErrNeedMoreData)
+		c, err := f.Feed(data[len(data)-1:])
+		assert.Nil(t, err)
+		assert.Equal(t, 1000, c[0].(Array).Length())
+		assert.Equal(t, int64(len(data)), f.InputOffset())
+		assert.Zero(t, f.Buffered())
+	})
+
+	t.Run("zero copy", func(t *testing.T) {
+		data := append(mustReadFile("bytes"), 0xff)
+		f := NewFeedDecoder(WithZeroCopy())
+		defer f.Close()
+		c, err := f.Feed(data[:len(data)-1])
+		assert.Nil(t, err)
+		// More input must not overwrite the decoded array.
+		_, err = f.Feed(mustReadFile("long-string")[4:])
+		assert.Nil(t, err)
+		b, _ := c[0].(Array).Bytes()
+		assert.Equal(t, byteArrayData("11223344"), b)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, tc := range []struct {
+			data     []byte
+			expected string
+		}{
+			{[]byte{0xac, 0xed, 0x00, 0x05, 0xff}, "d.readContent: unexpected terminal constant: ff"},
+			{[]byte{0xac}, ErrNeedMoreData.Error()},
+			{[]byte{0xca, 0xfe, 0xba, 0xbe}, "invalid stream: incorrect magic"},
+		} {
+			f := NewFeedDecoder()
+			_, err := f.Feed(tc.data)
+			assert.EqualError(t, err, tc.expected)
+			assert.Nil(t, f.Close())
+		}
+	})
+}
+
+func TestFeedDecoder_Close(t *testing.T) {
+	before := runtime.NumGoroutine()
+	f := NewFeedDecoder()
+	_, err := f.Feed(mustReadFile("object")[:20])
+	assert.ErrorIs(t, err, ErrNeedMoreData)
+	assert.Equal(t, before+1, runtime.NumGoroutine())
+	assert.Nil(t, f.Close())
+	assert.Equal(t, before, runtime.NumGoroutine())
+	_, err = f.Feed(mustReadFile("object")[20:])
+	assert.EqualError(t, err, "feed decoder is closed")
+	assert.Nil(t, f.Close())
+}
+
+func TestFeedDecoder_goroutine(t *testing.T) {
+	// The decoding goroutine stops on its own once an element fails to
+	// decode, or the input ends between elements.
+	before := runtime.NumGoroutine()
+	f := NewFeedDecoder()
+	_, err := f.Feed([]byte{0xac, 0xed, 0x00})
+	assert.ErrorIs(t, err, ErrNeedMoreData)
+	assert.Equal(t, before+1, runtime.NumGoroutine())
+	_, err = f.Feed([]byte{0x05, 0xff})
+	assert.EqualError(t, err, "d.readContent: unexpected terminal constant: ff")
+	assert.Equal(t, before, runtime.NumGoroutine())
+
+	f = NewFeedDecoder()
+	_, err = f.Feed(mustReadFile("object"))
+	assert.Nil(t, err)
+	assert.Equal(t, before, runtime.NumGoroutine())
+}
+
+func TestFeedDecoder_recovery(t *testing.T) {
+	object := mustReadFile("object")
+	data := append(append([]byte{}, object[:4]...), 0xff, 0x01)
+	data = append(data, object[4:]...)
+
+	f := NewFeedDecoder(WithRecovery())
+	defer f.Close()
+	_, err := f.Feed(data[:100])
+	assert.ErrorIs(t, err, ErrNeedMoreData)
+	c, err := f.Feed(data[100:])
+	assert.Nil(t, err)
+	assert.Equal(t, []Content{foo1}, c)
+	assert.Len(t, f.Diagnostics(), 1)
+	assert.Equal(t, "offset 5: [0]: unexpected terminal constant: ff (skipped 1 bytes)", f.Diagnostics()[0].String())
+}
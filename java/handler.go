@@ -0,0 +1,81 @@
+package java
+
+import "errors"
+
+// A Handler receives the contents of an element as Decoder.Walk reads it.
+// Handles are reported as in Span.Handle, so that references can be matched
+// to the elements they refer to.
+//
+// The contents of an object are reported between StartObject and EndObject:
+// a Field event followed by the field's value for each field, root superclass
+// first, then the contents written by writeObject methods. Arrays are
+// reported likewise between StartArray and EndArray, and null values with
+// Null. Class descriptors are not reported separately; they are passed to
+// the events of the elements that use them. Class values, such as those of
+// Class<?> fields, are reported with Class.
+//
+// Returning an error from any method stops the walk; see ErrStopWalk.
+type Handler interface {
+	StartObject(class *Class, handle int) error
+	// Field precedes the value of the field name declared by class.
+	Field(class *Class, name string, typeCode TypeCode) error
+	// Primitive reports a primitive field value or array element, with the
+	// types that Decode uses.
+	Primitive(value Value) error
+	String(value string, handle int) error
+	Class(class *Class, handle int) error
+	Enum(class *Class, handle int, constant string) error
+	StartArray(class *Class, handle int, length int) error
+	// BlockData reports data written by writeObject or writeExternal. data
+	// is only valid until BlockData returns.
+	BlockData(data []byte) error
+	Reference(handle int) error
+	Null() error
+	EndArray() error
+	EndObject() error
+}
+
+// ErrStopWalk can be returned by a Handler to end Decoder.Walk early without
+// an error.
+var ErrStopWalk = errors.New("stop walk")
+
+// BaseHandler implements Handler by ignoring all events. It can be embedded
+// by handlers that are only interested in some of them.
+type BaseHandler struct{}
+
+func (BaseHandler) StartObject(*Class, int) error        { return nil }
+func (BaseHandler) Field(*Class, string, TypeCode) error { return nil }
+func (BaseHandler) Primitive(Value) error                { return nil }
+func (BaseHandler) String(string, int) error             { return nil }
+func (BaseHandler) Class(*Class, int) error              { return nil }
+func (BaseHandler) Enum(*Class, int, string) error       { return nil }
+func (BaseHandler) StartArray(*Class, int, int) error    { return nil }
+func (BaseHandler) BlockData([]byte) error               { return nil }
+func (BaseHandler) Reference(int) error                  { return nil }
+func (BaseHandler) Null() error                          { return nil }
+func (BaseHandler) EndArray() error                      { return nil }
+func (BaseHandler) EndObject() error                     { return nil }
+
+// Walk reads the next element of the stream and reports its contents to h,
+// without building it; handles are assigned as in Skip. If h returns
+// ErrStopWalk, Walk returns nil, and the decoder is left in the middle of the
+// element and should not be used further. Other errors from h are returned,
+// wrapped.
+func (d *Decoder) Walk(h Handler) error {
+	d.handler = h
+	defer func() { d.handler = nil }()
+	_, err := d.Skip()
+	if errors.Is(err, ErrStopWalk) {
+		return nil
+	}
+	return err
+}
+
+// muteHandler stops events until the returned function is called. It is used
+// while reading class descriptors and enum constant names, which are
+// reported as part of other events.
+func (d *Decoder) muteHandler() func() {
+	h := d.handler
+	d.handler = nil
+	return func() { d.handler = h }
+}
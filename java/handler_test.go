@@ -0,0 +1,191 @@
+package java
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoder_Walk(t *testing.T) {
+	t.Run("objects", func(t *testing.T) {
+		const node = "com.edutko.Main$Node"
+		events := walk(t, "Node")
+		assert.Equal(t, []string{
+			"StartObject " + node + " 0x7e0003",
+			"Field " + node + ".name L", "String root 0x7e0004",
+			"Field " + node + ".next L", "StartObject " + node + " 0x7e0005",
+			"Field " + node + ".name L", "String a 0x7e0006",
+			"Field " + node + ".next L", "StartObject " + node + " 0x7e0007",
+			"Field " + node + ".name L", "String b 0x7e0008",
+			"Field " + node + ".next L", "Null",
+			"Field " + node + ".parent L", "Reference 0x7e0003",
+			"EndObject",
+			"Field " + node + ".parent L", "Reference 0x7e0003",
+			"EndObject",
+			"Field " + node + ".parent L", "Null",
+			"EndObject",
+		}, events)
+	})
+
+	t.Run("annotations", func(t *testing.T) {
+		events := walk(t, "ArrayList")
+		assert.Equal(t, []string{
+			"StartObject java.util.ArrayList 0x7e0001",
+			"Field java.util.ArrayList.size I", "Primitive 4",
+			"BlockData 00000004",
+			"StartObject java.lang.Integer 0x7e0004",
+			"Field java.lang.Integer.value I", "Primitive 11184810",
+		}, events[:7])
+		assert.Equal(t, "EndObject", events[len(events)-1])
+	})
+
+	t.Run("arrays", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"StartArray [I 0x7e0001 3",
+			"Primitive 1", "Primitive -2", "Primitive 2147483647",
+			"EndArray",
+		}, walk(t, "ints"))
+		assert.Equal(t, []string{
+			"StartArray [[I 0x7e0001 4",
+			"StartArray [I 0x7e0003 2", "Primitive 1", "Primitive 2", "EndArray",
+			"StartArray [I 0x7e0004 1", "Primitive 3", "EndArray",
+			"Null",
+			"StartArray [I 0x7e0005 0", "EndArray",
+			"EndArray",
+		}, walk(t, "jagged"))
+	})
+
+	t.Run("enum", func(t *testing.T) {
+		assert.Equal(t, []string{"Enum com.edutko.Main$Status 0x7e0002 FUBAR"}, walk(t, "enum"))
+	})
+
+	t.Run("class", func(t *testing.T) {
+		// An Object[] holding String.class and a reference back to it.
+		data := unhex("aced000575720013" + hex.EncodeToString([]byte("[Ljava.lang.Object;")) +
+			"90ce589f1073296c02000078700000000276720010" + hex.EncodeToString([]byte("java.lang.String")) +
+			"a0f0a4387a3bb34202000078707100" + "7e0003")
+		h := &recordingHandler{}
+		assert.Nil(t, NewDecoder(bytes.NewReader(data)).Walk(h))
+		assert.Equal(t, []string{
+			"StartArray [Ljava.lang.Object; 0x7e0001 2",
+			"Class java.lang.String 0x7e0003",
+			"Reference 0x7e0003",
+			"EndArray",
+		}, h.events)
+	})
+
+	t.Run("long string", func(t *testing.T) {
+		events := walk(t, "long-string")
+		assert.Len(t, events, 1)
+		assert.Len(t, events[0], len("String  0x7e0000")+65536)
+	})
+
+	t.Run("stop early", func(t *testing.T) {
+		h := &recordingHandler{stopAfter: 3}
+		d := NewDecoder(bytes.NewReader(mustReadFile("objects")))
+		assert.Nil(t, d.Walk(h))
+		assert.Len(t, h.events, 3)
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		d := NewDecoder(bytes.NewReader(mustReadFile("objects")))
+		err := d.Walk(stringErrorHandler{})
+		assert.ErrorIs(t, err, errString)
+		assert.Contains(t, err.Error(), "d.readContent: ")
+	})
+
+	t.Run("handles", func(t *testing.T) {
+		// A walked element can be referred to by the next one.
+		header := len(StreamMagic) + 2
+		str := mustReadFile("string")
+		data := append(append([]byte{}, str...), byte(tcReference), 0x00, 0x7e, 0x00, 0x00)
+		data = append(data, str[header:]...)
+		d := NewDecoder(bytes.NewReader(data))
+		assert.Nil(t, d.Walk(BaseHandler{}))
+		c, err := d.DecodeAll(2)
+		assert.Nil(t, err)
+		assert.Equal(t, []Content{c[1], c[1]}, c)
+	})
+}
+
+// walk returns the events for the first element in the named test file.
+func walk(t *testing.T, name string) []string {
+	h := &recordingHandler{}
+	assert.Nil(t, NewDecoder(bytes.NewReader(mustReadFile(name))).Walk(h))
+	return h.events
+}
+
+type recordingHandler struct {
+	events    []string
+	stopAfter int
+}
+
+func (h *recordingHandler) record(format string, a ...any) error {
+	h.events = append(h.events, fmt.Sprintf(format, a...))
+	if len(h.events) == h.stopAfter {
+		return ErrStopWalk
+	}
+	return nil
+}
+
+func (h *recordingHandler) StartObject(class *Class, handle int) error {
+	return h.record("StartObject %s %#x", class.ClassName, handle)
+}
+
+func (h *recordingHandler) Field(class *Class, name string, typeCode TypeCode) error {
+	return h.record("Field %s.%s %c", class.ClassName, name, typeCode)
+}
+
+func (h *recordingHandler) Primitive(value Value) error {
+	return h.record("Primitive %v", value)
+}
+
+func (h *recordingHandler) String(value string, handle int) error {
+	return h.record("String %s %#x", value, handle)
+}
+
+func (h *recordingHandler) Class(class *Class, handle int) error {
+	return h.record("Class %s %#x", class.ClassName, handle)
+}
+
+func (h *recordingHandler) Enum(class *Class, handle int, constant string) error {
+	return h.record("Enum %s %#x %s", class.ClassName, handle, constant)
+}
+
+func (h *recordingHandler) StartArray(class *Class, handle int, length int) error {
+	return h.record("StartArray %s %#x %d", class.ClassName, handle, length)
+}
+
+func (h *recordingHandler) BlockData(data []byte) error {
+	return h.record("BlockData %x", data)
+}
+
+func (h *recordingHandler) Reference(handle int) error {
+	return h.record("Reference %#x", handle)
+}
+
+func (h *recordingHandler) Null() error {
+	return h.record("Null")
+}
+
+func (h *recordingHandler) EndArray() error {
+	return h.record("EndArray")
+}
+
+func (h *recordingHandler) EndObject() error {
+	return h.record("EndObject")
+}
+
+var errString = errors.New("no strings")
+
+type stringErrorHandler struct {
+	BaseHandler
+}
+
+func (stringErrorHandler) String(string, int) error {
+	return errString
+}
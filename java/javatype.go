@@ -0,0 +1,208 @@
+package java
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JavaType is a parsed Java type: a primitive type, a class or an array.
+//
+// Types can be parsed from field descriptors ("Ljava/lang/String;", "[[I"),
+// as found in Field.ClassName, and from binary names ("java.lang.String",
+// "[Ljava.lang.String;"), as found in Class.ClassName. They can be formatted
+// in either of those forms or as canonical names ("java.lang.String[][]").
+type JavaType struct {
+	// Code is the type code of a primitive type, TypeObject for a class or
+	// TypeArray for an array.
+	Code TypeCode
+	// ClassName is the binary name of a class, e.g. "java.util.Map$Entry".
+	ClassName string
+	// Component is the component type of an array.
+	Component *JavaType
+}
+
+// ParseDescriptor parses a JVM field descriptor such as "I",
+// "Ljava/lang/String;" or "[[Ljava/util/Map$Entry;".
+func ParseDescriptor(descriptor string) (JavaType, error) {
+	t, rest, err := parseDescriptor(descriptor)
+	if err != nil {
+		return JavaType{}, fmt.Errorf("invalid descriptor %q: %w", descriptor, err)
+	}
+	if rest != "" {
+		return JavaType{}, fmt.Errorf("invalid descriptor %q: trailing %q", descriptor, rest)
+	}
+	return t, nil
+}
+
+func parseDescriptor(s string) (JavaType, string, error) {
+	if s == "" {
+		return JavaType{}, "", fmt.Errorf("missing type")
+	}
+	code := TypeCode(s[0])
+	switch code {
+	case TypeArray:
+		c, rest, err := parseDescriptor(s[1:])
+		if err != nil {
+			return JavaType{}, "", err
+		}
+		return JavaType{Code: TypeArray, Component: &c}, rest, nil
+	case TypeObject:
+		end := strings.IndexByte(s, ';')
+		if end < 0 {
+			return JavaType{}, "", fmt.Errorf("unterminated class name")
+		}
+		if end == 1 {
+			return JavaType{}, "", fmt.Errorf("empty class name")
+		}
+		return JavaType{Code: TypeObject, ClassName: strings.ReplaceAll(s[1:end], "/", ".")}, s[end+1:], nil
+	}
+	if _, ok := primitiveNames[code]; !ok {
+		return JavaType{}, "", fmt.Errorf("unknown type code: '%c'", code)
+	}
+	return JavaType{Code: code}, s[1:], nil
+}
+
+// ParseClassName parses a name as returned by Java's Class.getName: a binary
+// class name such as "java.util.Map$Entry", an array class name such as
+// "[Ljava.lang.String;" or "[[I", or a primitive type name such as "int".
+//
+// Canonical names cannot be parsed, because they do not distinguish nested
+// classes from packages.
+func ParseClassName(name string) (JavaType, error) {
+	if strings.HasPrefix(name, "[") {
+		t, err := ParseDescriptor(name)
+		if err != nil || strings.Contains(name, "/") {
+			return JavaType{}, fmt.Errorf("invalid class name %q", name)
+		}
+		return t, nil
+	}
+	for code, n := range primitiveNames {
+		if n == name {
+			return JavaType{Code: code}, nil
+		}
+	}
+	if name == "" || strings.ContainsAny(name, "[;/") {
+		return JavaType{}, fmt.Errorf("invalid class name %q", name)
+	}
+	return JavaType{Code: TypeObject, ClassName: name}, nil
+}
+
+func (t JavaType) IsPrimitive() bool {
+	return t.Code != TypeObject && t.Code != TypeArray
+}
+
+func (t JavaType) IsArray() bool {
+	return t.Code == TypeArray
+}
+
+func (t JavaType) IsClass() bool {
+	return t.Code == TypeObject
+}
+
+// Dimensions returns the number of dimensions of an array type, or 0.
+func (t JavaType) Dimensions() int {
+	n := 0
+	for ; t.Code == TypeArray && t.Component != nil; t = *t.Component {
+		n++
+	}
+	return n
+}
+
+// ElementType returns the innermost component type of an array type, e.g.
+// int for int[][]. Other types are returned unchanged.
+func (t JavaType) ElementType() JavaType {
+	for t.Code == TypeArray && t.Component != nil {
+		t = *t.Component
+	}
+	return t
+}
+
+// Package returns the package of a class, e.g. "java.util" for
+// java.util.Map$Entry.
+func (t JavaType) Package() string {
+	if i := strings.LastIndexByte(t.ClassName, '.'); i >= 0 {
+		return t.ClassName[:i]
+	}
+	return ""
+}
+
+// NestedNames returns the names of a class and the classes enclosing it,
+// outermost first, e.g. ["Map", "Entry"] for java.util.Map$Entry.
+func (t JavaType) NestedNames() []string {
+	if !t.IsClass() {
+		return nil
+	}
+	return strings.Split(strings.TrimPrefix(t.ClassName, t.Package()+"."), "$")
+}
+
+// SimpleName returns the unqualified name of a primitive type or class,
+// e.g. "Entry" for java.util.Map$Entry, or "String[]" for String[].
+func (t JavaType) SimpleName() string {
+	switch t.Code {
+	case TypeArray:
+		return t.ElementType().SimpleName() + strings.Repeat("[]", t.Dimensions())
+	case TypeObject:
+		names := t.NestedNames()
+		return names[len(names)-1]
+	}
+	return primitiveNames[t.Code]
+}
+
+// Descriptor returns the type as a JVM field descriptor, e.g.
+// "[Ljava/lang/String;".
+func (t JavaType) Descriptor() string {
+	switch t.Code {
+	case TypeArray:
+		return "[" + t.component().Descriptor()
+	case TypeObject:
+		return "L" + strings.ReplaceAll(t.ClassName, ".", "/") + ";"
+	}
+	return string(t.Code)
+}
+
+// BinaryName returns the type's name as Java's Class.getName would, e.g.
+// "int", "java.util.Map$Entry" or "[Ljava.lang.String;". This is the form
+// used by Class.ClassName.
+func (t JavaType) BinaryName() string {
+	switch t.Code {
+	case TypeArray:
+		return strings.ReplaceAll(t.Descriptor(), "/", ".")
+	case TypeObject:
+		return t.ClassName
+	}
+	return primitiveNames[t.Code]
+}
+
+// CanonicalName returns the type's name as it would appear in Java source,
+// e.g. "java.util.Map.Entry" or "java.lang.String[][]".
+func (t JavaType) CanonicalName() string {
+	switch t.Code {
+	case TypeArray:
+		return t.component().CanonicalName() + "[]"
+	case TypeObject:
+		return strings.ReplaceAll(t.ClassName, "$", ".")
+	}
+	return primitiveNames[t.Code]
+}
+
+func (t JavaType) String() string {
+	return t.CanonicalName()
+}
+
+func (t JavaType) component() JavaType {
+	if t.Component == nil {
+		return JavaType{}
+	}
+	return *t.Component
+}
+
+var primitiveNames = map[TypeCode]string{
+	TypeByte:    "byte",
+	TypeChar:    "char",
+	TypeDouble:  "double",
+	TypeFloat:   "float",
+	TypeInteger: "int",
+	TypeLong:    "long",
+	TypeShort:   "short",
+	TypeBoolean: "boolean",
+}
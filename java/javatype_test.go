@@ -0,0 +1,112 @@
+package java
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDescriptor(t *testing.T) {
+	testCases := []struct {
+		descriptor string
+		binary     string
+		canonical  string
+		simple     string
+		dimensions int
+	}{
+		{"I", "int", "int", "int", 0},
+		{"Z", "boolean", "boolean", "boolean", 0},
+		{"Ljava/lang/String;", "java.lang.String", "java.lang.String", "String", 0},
+		{"Ljava/util/Map$Entry;", "java.util.Map$Entry", "java.util.Map.Entry", "Entry", 0},
+		{"LNoPackage;", "NoPackage", "NoPackage", "NoPackage", 0},
+		{"[B", "[B", "byte[]", "byte[]", 1},
+		{"[[I", "[[I", "int[][]", "int[][]", 2},
+		{"[[Ljava/lang/String;", "[[Ljava.lang.String;", "java.lang.String[][]", "String[][]", 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.descriptor, func(t *testing.T) {
+			jt, err := ParseDescriptor(tc.descriptor)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.descriptor, jt.Descriptor())
+			assert.Equal(t, tc.binary, jt.BinaryName())
+			assert.Equal(t, tc.canonical, jt.CanonicalName())
+			assert.Equal(t, tc.canonical, jt.String())
+			assert.Equal(t, tc.simple, jt.SimpleName())
+			assert.Equal(t, tc.dimensions, jt.Dimensions())
+
+			fromBinary, err := ParseClassName(tc.binary)
+			assert.Nil(t, err)
+			assert.Equal(t, jt, fromBinary)
+		})
+	}
+}
+
+func TestParseDescriptor_errors(t *testing.T) {
+	testCases := []struct {
+		descriptor string
+		expected   string
+	}{
+		{"", `invalid descriptor "": missing type`},
+		{"[", `invalid descriptor "[": missing type`},
+		{"X", `invalid descriptor "X": unknown type code: 'X'`},
+		{"Ljava/lang/String", `invalid descriptor "Ljava/lang/String": unterminated class name`},
+		{"L;", `invalid descriptor "L;": empty class name`},
+		{"II", `invalid descriptor "II": trailing "I"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.descriptor, func(t *testing.T) {
+			_, err := ParseDescriptor(tc.descriptor)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestParseClassName_errors(t *testing.T) {
+	for _, name := range []string{"", "[", "[Ljava/lang/String;", "java.lang.String;", "int[]"} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseClassName(name)
+			assert.EqualError(t, err, "invalid class name \""+name+"\"")
+		})
+	}
+}
+
+func TestJavaType_parts(t *testing.T) {
+	jt, err := ParseClassName("com.example.Outer$Middle$Inner")
+	assert.Nil(t, err)
+	assert.True(t, jt.IsClass())
+	assert.False(t, jt.IsPrimitive())
+	assert.Equal(t, "com.example", jt.Package())
+	assert.Equal(t, []string{"Outer", "Middle", "Inner"}, jt.NestedNames())
+	assert.Equal(t, "Inner", jt.SimpleName())
+
+	arr, err := ParseClassName("[[Lcom.example.Outer$Inner;")
+	assert.Nil(t, err)
+	assert.True(t, arr.IsArray())
+	assert.Equal(t, "[Lcom.example.Outer$Inner;", arr.Component.BinaryName())
+	assert.Equal(t, "com.example.Outer$Inner", arr.ElementType().ClassName)
+	assert.Nil(t, arr.NestedNames())
+}
+
+func TestField_Type(t *testing.T) {
+	jt, err := Field{TypeCode: TypeArray, FieldName: "bars", ClassName: "[Lcom/edutko/Main$Bar;"}.Type()
+	assert.Nil(t, err)
+	assert.Equal(t, "com.edutko.Main.Bar[]", jt.CanonicalName())
+
+	jt, err = Field{TypeCode: TypeLong, FieldName: "l"}.Type()
+	assert.Nil(t, err)
+	assert.Equal(t, JavaType{Code: TypeLong}, jt)
+
+	_, err = Field{TypeCode: 'X', FieldName: "x"}.Type()
+	assert.EqualError(t, err, "unknown type code: 'X'")
+}
+
+func TestClass_Type(t *testing.T) {
+	jt, err := stringArray.Type()
+	assert.Nil(t, err)
+	assert.Equal(t, "java.lang.String[]", jt.CanonicalName())
+
+	_, err = (*Class)(nil).Type()
+	assert.EqualError(t, err, "no class descriptor")
+}
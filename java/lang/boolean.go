@@ -1,5 +1,11 @@
 package lang
 
+import "github.com/edutko/cafegopher/java"
+
 type Boolean struct {
 	Value bool `java:"value"`
 }
+
+func (b *Boolean) UnmarshalJava(c java.Content, state *java.UnmarshalState) error {
+	return unmarshalWrapper(c, state, "java.lang.Boolean", b, &b.Value)
+}
@@ -1,9 +1,15 @@
 package lang
 
+import "github.com/edutko/cafegopher/java"
+
 type Byte struct {
 	Value int8 `java:"value"`
 }
 
+func (b *Byte) UnmarshalJava(c java.Content, state *java.UnmarshalState) error {
+	return unmarshalWrapper(c, state, "java.lang.Byte", b, &b.Value)
+}
+
 func (b Byte) ByteValue() byte {
 	return byte(b.Value)
 }
@@ -1,5 +1,11 @@
 package lang
 
+import "github.com/edutko/cafegopher/java"
+
 type Character struct {
 	Value rune `java:"value"`
 }
+
+func (ch *Character) UnmarshalJava(c java.Content, state *java.UnmarshalState) error {
+	return unmarshalWrapper(c, state, "java.lang.Character", ch, &ch.Value)
+}
@@ -1,5 +1,11 @@
 package lang
 
+import "github.com/edutko/cafegopher/java"
+
 type Double struct {
 	Value float64 `java:"value"`
 }
+
+func (d *Double) UnmarshalJava(c java.Content, state *java.UnmarshalState) error {
+	return unmarshalWrapper(c, state, "java.lang.Double", d, &d.Value)
+}
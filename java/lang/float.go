@@ -1,5 +1,11 @@
 package lang
 
+import "github.com/edutko/cafegopher/java"
+
 type Float struct {
 	Value float32 `java:"value"`
 }
+
+func (f *Float) UnmarshalJava(c java.Content, state *java.UnmarshalState) error {
+	return unmarshalWrapper(c, state, "java.lang.Float", f, &f.Value)
+}
@@ -1,5 +1,11 @@
 package lang
 
+import "github.com/edutko/cafegopher/java"
+
 type Integer struct {
 	Value int `java:"value"`
 }
+
+func (i *Integer) UnmarshalJava(c java.Content, state *java.UnmarshalState) error {
+	return unmarshalWrapper(c, state, "java.lang.Integer", i, &i.Value)
+}
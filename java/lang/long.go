@@ -1,5 +1,11 @@
 package lang
 
+import "github.com/edutko/cafegopher/java"
+
 type Long struct {
 	Value int64 `java:"value"`
 }
+
+func (l *Long) UnmarshalJava(c java.Content, state *java.UnmarshalState) error {
+	return unmarshalWrapper(c, state, "java.lang.Long", l, &l.Value)
+}
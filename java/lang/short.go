@@ -1,5 +1,11 @@
 package lang
 
+import "github.com/edutko/cafegopher/java"
+
 type Short struct {
 	Value int16 `java:"value"`
 }
+
+func (s *Short) UnmarshalJava(c java.Content, state *java.UnmarshalState) error {
+	return unmarshalWrapper(c, state, "java.lang.Short", s, &s.Value)
+}
@@ -0,0 +1,24 @@
+package lang
+
+import (
+	"reflect"
+
+	"github.com/edutko/cafegopher/java"
+)
+
+// unmarshalWrapper stores the value held by an instance of the wrapper class
+// className, or a primitive value, in value. A null leaves the wrapper
+// unchanged.
+func unmarshalWrapper(c java.Content, state *java.UnmarshalState, className string, wrapper any, value any) error {
+	if c == nil {
+		return nil
+	}
+	o, ok := c.(java.Object)
+	if !ok {
+		return state.Unmarshal(c, value)
+	}
+	if o.GetClassName() != className {
+		return state.TypeError(c, reflect.TypeOf(wrapper).Elem())
+	}
+	return state.UnmarshalField(o.ClassData[className]["value"], className+".value", "Value", value)
+}
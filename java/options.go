@@ -0,0 +1,142 @@
+package java
+
+import "sync"
+
+// An Option configures a Decoder or a single call to UnmarshalWithOptions.
+// Settings that are not given fall back to the package defaults.
+type Option func(*options)
+
+type options struct {
+	packagePrefixes         []string
+	allowInt8ToByteCoercion bool
+	registry                *Registry
+	disallowUnknownFields   bool
+	collectErrors           bool
+	recordSpans             bool
+	recover                 bool
+	zeroCopy                bool
+}
+
+// WithPackagePrefixes sets the Go package path prefixes that are removed when
+// deriving a Java class name from a Go type, replacing the defaults.
+func WithPackagePrefixes(prefixes ...string) Option {
+	return func(o *options) {
+		o.packagePrefixes = append([]string(nil), prefixes...)
+	}
+}
+
+// WithInt8ToByteCoercion controls whether negative Java bytes may be stored in
+// Go uint8 values (two's complement), as is usually expected of byte arrays.
+func WithInt8ToByteCoercion(allow bool) Option {
+	return func(o *options) {
+		o.allowInt8ToByteCoercion = allow
+	}
+}
+
+// WithRegistry sets a registry that is consulted before DefaultRegistry.
+func WithRegistry(r *Registry) Option {
+	return func(o *options) {
+		o.registry = r
+	}
+}
+
+// WithDisallowUnknownFields is the option equivalent of
+// Decoder.DisallowUnknownFields.
+func WithDisallowUnknownFields() Option {
+	return func(o *options) {
+		o.disallowUnknownFields = true
+	}
+}
+
+// WithCollectErrors makes Unmarshal continue past values that cannot be
+// stored and return all such errors joined, rather than stopping at the first.
+func WithCollectErrors() Option {
+	return func(o *options) {
+		o.collectErrors = true
+	}
+}
+
+// WithSpans makes a Decoder record where each element appears in the stream;
+// see Decoder.Spans.
+func WithSpans() Option {
+	return func(o *options) {
+		o.recordSpans = true
+	}
+}
+
+// WithRecovery makes a Decoder return as much of a truncated or corrupt
+// stream as it can. Objects and arrays that could not be read completely are
+// returned with Incomplete set, and after a corrupt element the decoder skips
+// ahead to the next plausible object. The problems found are reported by
+// Decoder.Diagnostics rather than as errors; only the end of the input is
+// returned as an error.
+//
+// To find the next plausible object, the decoder looks up to about 1KB past
+// the bytes it skips. A reader that cannot seek back cannot be given those
+// bytes back, so after skipping a corrupt element, a Decoder reading from
+// such a reader may consume input beyond the last element it returns.
+func WithRecovery() Option {
+	return func(o *options) {
+		o.recover = true
+	}
+}
+
+// WithZeroCopy lets a Decoder created by NewBytesDecoder or
+// NewReaderAtDecoder return block data and the contents of primitive arrays as
+// sub-slices of its input, or of the windows it reads the input into, rather
+// than copies; see Array.Raw. The input must not be modified while such values
+// are in use. Decoders that read from an io.Reader ignore this option.
+func WithZeroCopy() Option {
+	return func(o *options) {
+		o.zeroCopy = true
+	}
+}
+
+func newOptions(opts []Option) options {
+	defaultsMu.RLock()
+	o := options{
+		packagePrefixes:         defaultPackagePrefixes,
+		allowInt8ToByteCoercion: defaultAllowInt8ToByteCoercion,
+	}
+	defaultsMu.RUnlock()
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// AddPackagePrefixes adds to the default package prefixes.
+func AddPackagePrefixes(prefixes ...string) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	p := make([]string, 0, len(defaultPackagePrefixes)+len(prefixes))
+	defaultPackagePrefixes = append(append(p, defaultPackagePrefixes...), prefixes...)
+}
+
+// SetPackagePrefixes replaces the default package prefixes.
+func SetPackagePrefixes(prefixes ...string) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	defaultPackagePrefixes = append([]string(nil), prefixes...)
+}
+
+func AllowInt8ToByteCoercion() {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	defaultAllowInt8ToByteCoercion = true
+}
+
+func PreventInt8ToByteCoercion() {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	defaultAllowInt8ToByteCoercion = false
+}
+
+// The defaults are copied into each Decoder and each Unmarshal call, so
+// changing them never affects decoding that is already in progress.
+var (
+	defaultsMu                     sync.RWMutex
+	defaultPackagePrefixes         = []string{"github.com/edutko/cafegopher"}
+	defaultAllowInt8ToByteCoercion = true
+)
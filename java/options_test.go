@@ -0,0 +1,74 @@
+package java
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalWithOptions(t *testing.T) {
+	t.Run("int8 to byte coercion", func(t *testing.T) {
+		var actual byte
+		err := UnmarshalWithOptions(mustReadFile("Byte"), &actual, WithInt8ToByteCoercion(false))
+		assert.Equal(t, "unmarshalValue: cannot cast java.lang.Byte to uint8", err.Error())
+
+		assert.Nil(t, UnmarshalWithOptions(mustReadFile("Byte"), &actual, WithInt8ToByteCoercion(true)))
+		assert.Equal(t, byte(255), actual)
+	})
+
+	t.Run("registry", func(t *testing.T) {
+		r := NewRegistry()
+		r.Register("com.edutko.Main$Bar", bar{})
+		var actual []any
+		assert.Nil(t, UnmarshalWithOptions(mustReadFile("objects"), &actual, WithRegistry(r)))
+		assert.Equal(t, 3, len(actual))
+	})
+
+	t.Run("disallow unknown fields", func(t *testing.T) {
+		var actual struct {
+			I int `java:"com.edutko.Main$Foo.i"`
+		}
+		err := UnmarshalWithOptions(mustReadFile("object"), &actual, WithDisallowUnknownFields())
+		assert.ErrorIs(t, err, ErrUnmappedField)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			allow := i%2 == 0
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var actual byte
+				d := NewDecoder(bytes.NewReader(mustReadFile("Byte")), WithInt8ToByteCoercion(allow))
+				err := d.Unmarshal(&actual)
+				assert.Equal(t, allow, err == nil)
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestOptions_packagePrefixes(t *testing.T) {
+	typ := reflect.TypeOf(foo{})
+	assert.Equal(t, "java.foo", newUnmarshaler().classNameForType(typ))
+	assert.Equal(t, "cafegopher.java.foo", newUnmarshaler(WithPackagePrefixes("github.com/edutko")).classNameForType(typ))
+	assert.Equal(t, "github.com.edutko.cafegopher.java.foo", newUnmarshaler(WithPackagePrefixes()).classNameForType(typ))
+}
+
+func TestOptions_defaults(t *testing.T) {
+	AddPackagePrefixes("github.com/edutko/cafegopher/java")
+	PreventInt8ToByteCoercion()
+	defer SetPackagePrefixes("github.com/edutko/cafegopher")
+	defer AllowInt8ToByteCoercion()
+
+	o := newOptions(nil)
+	assert.Equal(t, []string{"github.com/edutko/cafegopher", "github.com/edutko/cafegopher/java"}, o.packagePrefixes)
+	assert.False(t, o.allowInt8ToByteCoercion)
+
+	o = newOptions([]Option{WithInt8ToByteCoercion(true)})
+	assert.True(t, o.allowInt8ToByteCoercion)
+}
@@ -1,26 +1,296 @@
 package java
 
 import (
+	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
+	"math"
 	"unicode/utf16"
 )
 
+// binaryReader reads big-endian values from r, serving them from an internal
+// buffer so that primitive reads do not allocate.
+//
+// The decoder must not consume input beyond the elements it decodes, so
+// binaryReader only reads ahead when r is seekable; release then seeks back
+// over the bytes that were read but not consumed. Other readers are asked for
+// exactly the bytes needed, and callers that want those reads buffered can
+// wrap the reader in a bufio.Reader themselves.
 type binaryReader struct {
 	r io.Reader
+	n int64 // bytes consumed so far
+
+	// buf[start:end] holds bytes that were read from r but not yet consumed.
+	buf        []byte
+	start, end int
+	// seeker is r if it can seek, and nil otherwise.
+	seeker io.Seeker
+	// inMemory is set when buf holds the whole input; see newBytesReader.
+	inMemory bool
+	// windowed is set when r is a section of an io.ReaderAt; see
+	// newWindowReader. size is then the length of the section.
+	windowed bool
+	size     int64
+	// more, if set, is called when an in-memory input runs out, and returns
+	// data to add to it; see FeedDecoder.
+	more func() ([]byte, bool)
+}
+
+// bufferSize is the size up to which binaryReader grows the buffer that it
+// reads seekable readers into. Values up to this size are read without
+// allocating.
+const (
+	minBufferSize = 256
+	bufferSize    = 4096
+)
+
+// windowSize is the size up to which a windowed binaryReader grows its
+// windows.
+const windowSize = 1 << 20
+
+func newBinaryReader(r io.Reader) *binaryReader {
+	br := &binaryReader{r: r}
+	// Some readers, such as os.File for a pipe, implement io.Seeker but
+	// cannot seek.
+	if s, ok := r.(io.Seeker); ok {
+		if _, err := s.Seek(0, io.SeekCurrent); err == nil {
+			br.seeker = s
+		}
+	}
+	return br
+}
+
+// newBytesReader returns a binaryReader that reads data in place. The slices
+// returned by next and readView are sub-slices of data, and stay valid.
+func newBytesReader(data []byte) *binaryReader {
+	return &binaryReader{r: bytes.NewReader(nil), buf: data, end: len(data), inMemory: true}
+}
+
+// newWindowReader returns a binaryReader that reads the size bytes of r that
+// start at off into windows that are never reused, so that the slices returned
+// by next and readView stay valid.
+func newWindowReader(r io.ReaderAt, off, size int64) *binaryReader {
+	sr := io.NewSectionReader(r, off, size)
+	return &binaryReader{r: sr, seeker: sr, windowed: true, size: size}
+}
+
+// append adds data to an in-memory input. Consumed bytes are dropped, but
+// never overwritten, so that slices returned by next and readView stay valid.
+func (r *binaryReader) append(data []byte) {
+	r.buf = append(r.buf[r.start:r.end], data...)
+	r.start, r.end = 0, len(r.buf)
+}
+
+func (r *binaryReader) buffered() int {
+	return r.end - r.start
+}
+
+// fill makes count bytes available in the buffer, reading from r as needed.
+// It returns io.EOF or io.ErrUnexpectedEOF if r ends first.
+func (r *binaryReader) fill(count int) error {
+	if r.buffered() >= count {
+		return nil
+	}
+	if r.inMemory {
+		for r.more != nil && r.buffered() < count {
+			data, ok := r.more()
+			if !ok {
+				break
+			}
+			r.append(data)
+		}
+		if r.buffered() >= count {
+			return nil
+		}
+		if r.buffered() > 0 {
+			return io.ErrUnexpectedEOF
+		}
+		return io.EOF
+	}
+	if r.windowed && int64(count) > r.size-r.n {
+		// Read what is left rather than allocate a window for a corrupt
+		// length.
+		if err := r.fill(int(r.size - r.n)); err != nil {
+			return err
+		}
+		if r.buffered() > 0 {
+			return io.ErrUnexpectedEOF
+		}
+		return io.EOF
+	}
+	if len(r.buf)-r.start < count {
+		buf := r.buf
+		if r.windowed || len(buf) < count || r.seeker != nil && len(buf) < bufferSize {
+			buf = make([]byte, r.grownSize(count))
+		}
+		copy(buf, r.buf[r.start:r.end])
+		r.buf, r.start, r.end = buf, 0, r.buffered()
+	}
+
+	want := count - r.buffered()
+	limit := r.end + want
+	if r.seeker != nil {
+		limit = len(r.buf)
+	}
+	n, err := io.ReadAtLeast(r.r, r.buf[r.end:limit], want)
+	r.end += n
+	if err == io.EOF && r.buffered() > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// grownSize returns the size to grow the buffer to so that it can hold count
+// bytes. The buffer starts small and doubles each time it is refilled, so that
+// decoding small streams stays cheap. Windows grow the same way, since each
+// refill allocates a new one.
+func (r *binaryReader) grownSize(count int) int {
+	size := 2 * len(r.buf)
+	if size < minBufferSize {
+		size = minBufferSize
+	}
+	max := bufferSize
+	if r.windowed {
+		max = windowSize
+	}
+	if size > max {
+		size = max
+	}
+	if size < count {
+		size = count
+	}
+	return size
+}
+
+// next consumes count bytes and returns them. The result is only valid until
+// the next call to a binaryReader method. Like io.ReadFull, next consumes
+// whatever is left of the input if it ends early.
+func (r *binaryReader) next(count int) ([]byte, error) {
+	if err := r.fill(count); err != nil {
+		r.consume(r.buffered())
+		return nil, err
+	}
+	b := r.buf[r.start : r.start+count : r.start+count]
+	r.consume(count)
+	return b, nil
+}
+
+// peek returns the next count bytes without consuming them. It returns fewer
+// bytes, and an error, at the end of the input.
+func (r *binaryReader) peek(count int) ([]byte, error) {
+	err := r.fill(count)
+	if count > r.buffered() {
+		count = r.buffered()
+	}
+	return r.buf[r.start : r.start+count], err
+}
+
+// release discards the buffer, seeking r back over the bytes that were read
+// ahead, so that r is positioned after the last byte consumed.
+func (r *binaryReader) release() error {
+	if r.seeker == nil || r.buffered() == 0 {
+		return nil
+	}
+	_, err := r.seeker.Seek(int64(-r.buffered()), io.SeekCurrent)
+	r.start, r.end = 0, 0
+	if r.windowed {
+		r.buf = nil
+	}
+	return err
 }
 
+// maxPrealloc limits how much readBytes allocates before any data has been
+// read, so that a corrupt length cannot exhaust memory.
+const maxPrealloc = 1 << 20
+
+// readBytes consumes count bytes and returns them in a newly allocated slice.
 func (r *binaryReader) readBytes(count int64) ([]byte, error) {
-	b := make([]byte, count)
-	_, err := io.ReadFull(r.r, b)
+	if count < 0 {
+		return nil, fmt.Errorf("invalid length: %d", count)
+	}
+	if count <= bufferSize || count <= int64(r.buffered()) || r.inMemory {
+		b, err := r.next(int(count))
+		if err != nil {
+			return nil, err
+		}
+		return append(make([]byte, 0, count), b...), nil
+	}
+
+	// The buffer holds less than count bytes; the rest is read directly.
+	if count <= maxPrealloc {
+		b := make([]byte, count)
+		k := copy(b, r.buf[r.start:r.end])
+		r.consume(k)
+		n, err := io.ReadFull(r.r, b[k:])
+		r.n += int64(n)
+		if err == io.EOF && k > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(maxPrealloc)
+	k, _ := buf.Write(r.buf[r.start:r.end])
+	r.consume(k)
+	n, err := io.CopyN(&buf, r.r, count-int64(k))
+	r.n += n
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
 	if err != nil {
 		return nil, err
 	}
-	return b, nil
+	return buf.Bytes(), nil
+}
+
+// readView consumes count bytes and returns them, without copying them if
+// they fit in the buffer. Like next, the result is only valid until the next
+// call to a binaryReader method, unless the input is in memory or windowed.
+func (r *binaryReader) readView(count int64) ([]byte, error) {
+	if count >= 0 && (count <= bufferSize || r.inMemory || r.windowed) {
+		return r.next(int(count))
+	}
+	return r.readBytes(count)
+}
+
+// consume consumes k buffered bytes.
+func (r *binaryReader) consume(k int) {
+	r.start += k
+	r.n += int64(k)
+}
+
+// skip discards count bytes.
+func (r *binaryReader) skip(count int64) error {
+	if count < 0 {
+		return fmt.Errorf("invalid length: %d", count)
+	}
+	if r.inMemory {
+		_, err := r.next(int(count))
+		return err
+	}
+	k := int64(r.buffered())
+	if count < k {
+		k = count
+	}
+	r.consume(int(k))
+	if k == count {
+		return nil
+	}
+	n, err := io.CopyN(io.Discard, r.r, count-k)
+	r.n += n
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
 }
 
 func (r *binaryReader) readByte() (byte, error) {
-	b, err := r.readBytes(1)
+	b, err := r.next(1)
 	if err != nil {
 		return 0, err
 	}
@@ -28,11 +298,11 @@ func (r *binaryReader) readByte() (byte, error) {
 }
 
 func (r *binaryReader) readBoolean() (bool, error) {
-	b, err := r.readBytes(1)
+	b, err := r.readByte()
 	if err != nil {
 		return false, err
 	}
-	return b[0] != 0, nil
+	return b != 0, nil
 }
 
 func (r *binaryReader) readChar() (rune, error) {
@@ -44,33 +314,31 @@ func (r *binaryReader) readChar() (rune, error) {
 }
 
 func (r *binaryReader) readFloat32() (float32, error) {
-	var f float32
-	err := binary.Read(r.r, binary.BigEndian, &f)
+	b, err := r.next(4)
 	if err != nil {
 		return 0, err
 	}
-	return f, nil
+	return math.Float32frombits(binary.BigEndian.Uint32(b)), nil
 }
 
 func (r *binaryReader) readFloat64() (float64, error) {
-	var f float64
-	err := binary.Read(r.r, binary.BigEndian, &f)
+	b, err := r.next(8)
 	if err != nil {
 		return 0, err
 	}
-	return f, nil
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
 }
 
 func (r *binaryReader) readInt8() (int8, error) {
-	b, err := r.readBytes(1)
+	b, err := r.readByte()
 	if err != nil {
 		return 0, err
 	}
-	return int8(b[0]), nil
+	return int8(b), nil
 }
 
 func (r *binaryReader) readInt16() (int16, error) {
-	b, err := r.readBytes(2)
+	b, err := r.next(2)
 	if err != nil {
 		return 0, err
 	}
@@ -78,7 +346,7 @@ func (r *binaryReader) readInt16() (int16, error) {
 }
 
 func (r *binaryReader) readInt32() (int32, error) {
-	b, err := r.readBytes(4)
+	b, err := r.next(4)
 	if err != nil {
 		return 0, err
 	}
@@ -86,7 +354,7 @@ func (r *binaryReader) readInt32() (int32, error) {
 }
 
 func (r *binaryReader) readInt64() (int64, error) {
-	b, err := r.readBytes(8)
+	b, err := r.next(8)
 	if err != nil {
 		return 0, err
 	}
@@ -106,7 +374,7 @@ func (r *binaryReader) readUint8() (uint8, error) {
 }
 
 func (r *binaryReader) readUint16() (uint16, error) {
-	b, err := r.readBytes(2)
+	b, err := r.next(2)
 	if err != nil {
 		return 0, err
 	}
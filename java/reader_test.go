@@ -0,0 +1,69 @@
+package java
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinaryReader_release(t *testing.T) {
+	data := []byte{0x00, 0x00, 0x00, 0x2a, 0x01, 0x02, 0x03}
+
+	t.Run("seekable", func(t *testing.T) {
+		src := bytes.NewReader(data)
+		r := newBinaryReader(src)
+		i, err := r.readInt32()
+		assert.Nil(t, err)
+		assert.Equal(t, int32(42), i)
+		assert.Equal(t, 0, src.Len(), "seekable readers are read ahead")
+
+		assert.Nil(t, r.release())
+		assert.Equal(t, 3, src.Len())
+		assert.Equal(t, int64(4), r.n)
+	})
+
+	t.Run("unseekable", func(t *testing.T) {
+		src := bytes.NewReader(data)
+		r := newBinaryReader(struct{ io.Reader }{src})
+		_, err := r.readInt32()
+		assert.Nil(t, err)
+		assert.Equal(t, 3, src.Len())
+
+		b, err := r.peek(2)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0x01, 0x02}, b)
+		assert.Equal(t, 1, src.Len())
+		assert.Nil(t, r.release())
+
+		b, err = r.readBytes(3)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0x01, 0x02, 0x03}, b)
+	})
+}
+
+func TestBinaryReader_truncated(t *testing.T) {
+	r := newBinaryReader(bytes.NewReader([]byte{0x01, 0x02, 0x03}))
+	_, err := r.readInt16()
+	assert.Nil(t, err)
+	_, err = r.readInt32()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, int64(3), r.n)
+	_, err = r.readByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestBinaryReader_allocs(t *testing.T) {
+	r := newBinaryReader(bytes.NewReader(make([]byte, 1<<16)))
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = r.readInt8()
+		_, _ = r.readInt16()
+		_, _ = r.readInt32()
+		_, _ = r.readInt64()
+		_, _ = r.readFloat32()
+		_, _ = r.readFloat64()
+		_, _ = r.readBoolean()
+	})
+	assert.Zero(t, allocs)
+}
@@ -0,0 +1,135 @@
+package java
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A Diagnostic describes a problem that a Decoder created with WithRecovery
+// worked around.
+type Diagnostic struct {
+	// Offset is the input offset at which the problem was detected.
+	Offset int64
+	// Path locates the element being read, in the form used by Span.Path.
+	Path string
+	Err  error
+	// Skipped is the number of bytes discarded to find the next element.
+	Skipped int64
+}
+
+func (d Diagnostic) String() string {
+	s := fmt.Sprintf("offset %d: %s: %v", d.Offset, d.Path, d.Err)
+	if d.Skipped > 0 {
+		s += fmt.Sprintf(" (skipped %d bytes)", d.Skipped)
+	}
+	return s
+}
+
+// Diagnostics returns the problems found so far by a Decoder created with
+// WithRecovery.
+func (d *Decoder) Diagnostics() []Diagnostic {
+	return d.diagnostics
+}
+
+// isPartial reports whether c is an element that can be returned even though
+// it was not read completely.
+func isPartial(c Content) bool {
+	switch c.(type) {
+	case Object, Array:
+		return true
+	}
+	return false
+}
+
+// noteError remembers where err occurred, so that the diagnostic for it
+// points at the innermost element rather than the top-level one.
+func (d *Decoder) noteError(err error) {
+	if err != nil && d.opts.recover && d.failure == nil {
+		d.failure = &Diagnostic{Offset: d.r.n, Path: joinPath(d.path, "/"), Err: err}
+	}
+}
+
+// recoverFrom records a diagnostic for err and, unless the input was
+// truncated, discards input up to the next plausible element.
+func (d *Decoder) recoverFrom(err error) {
+	diag := Diagnostic{Offset: d.r.n, Err: err}
+	if d.failure != nil {
+		diag.Offset, diag.Path = d.failure.Offset, d.failure.Path
+		d.failure = nil
+	}
+	if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		diag.Skipped = d.resync()
+	}
+	d.diagnostics = append(d.diagnostics, diag)
+}
+
+// resync discards input until the next plausible start of an object or class
+// descriptor, or the end of the input, and returns the number of bytes
+// discarded. Handles assigned by the discarded bytes are lost, so references
+// that follow may be wrong. Checking a candidate start peeks at up to
+// 1028 bytes, which are only returned to a reader that can seek.
+func (d *Decoder) resync() int64 {
+	start := d.r.n
+	for {
+		b, _ := d.r.peek(2)
+		if len(b) == 0 {
+			break
+		}
+		if d.isPlausibleStart() {
+			break
+		}
+		d.r.consume(1)
+	}
+	return d.r.n - start
+}
+
+func (d *Decoder) isPlausibleStart() bool {
+	b, _ := d.r.peek(2)
+	switch {
+	case len(b) < 2:
+		return false
+	case TypeCode(b[0]) == tcObject && TypeCode(b[1]) == tcClassDesc:
+		return d.isPlausibleClassName(2)
+	case TypeCode(b[0]) == tcObject && TypeCode(b[1]) == tcReference:
+		b, err := d.r.peek(6)
+		if err != nil {
+			return false
+		}
+		h := handle(int32(b[2])<<24 | int32(b[3])<<16 | int32(b[4])<<8 | int32(b[5]))
+		_, ok := d.o[h].(*Class)
+		return ok
+	case TypeCode(b[0]) == tcClassDesc:
+		return d.isPlausibleClassName(1)
+	}
+	return false
+}
+
+// isPlausibleClassName reports whether the input at offset looks like a
+// length-prefixed Java class name.
+func (d *Decoder) isPlausibleClassName(offset int) bool {
+	b, err := d.r.peek(offset + 2)
+	if err != nil {
+		return false
+	}
+	l := int(b[offset])<<8 | int(b[offset+1])
+	if l == 0 || l > 1024 {
+		return false
+	}
+	b, err = d.r.peek(offset + 2 + l)
+	if err != nil {
+		return false
+	}
+	for i, c := range b[offset+2:] {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == '$':
+		case c >= '0' && c <= '9', c == '.', c == '[', c == ';':
+			if i == 0 && c != '[' {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package java
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoder_recovery(t *testing.T) {
+	const node = "com.edutko.Main$Node"
+	header := len(StreamMagic) + 2
+
+	t.Run("truncated", func(t *testing.T) {
+		d := NewDecoder(bytes.NewReader(mustReadFile("Node")[:130]), WithRecovery())
+		c, err := d.Decode()
+		assert.Nil(t, err)
+
+		root := c.(Object)
+		assert.True(t, root.Incomplete)
+		assert.Equal(t, "root", root.ClassData[node]["name"])
+		assert.Equal(t, []string{node + ".parent"}, root.MissingFields())
+
+		next := root.ClassData[node]["next"].(Object)
+		assert.True(t, next.Incomplete)
+		assert.Equal(t, []string{node + ".name", node + ".next", node + ".parent"}, next.MissingFields())
+
+		diags := d.Diagnostics()
+		assert.Len(t, diags, 1)
+		assert.Equal(t, int64(130), diags[0].Offset)
+		assert.Equal(t, "[0]/"+node+".next/"+node+".name", diags[0].Path)
+		assert.ErrorIs(t, diags[0].Err, io.ErrUnexpectedEOF)
+		assert.Zero(t, diags[0].Skipped)
+
+		_, err = d.Decode()
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("resync", func(t *testing.T) {
+		object := mustReadFile("object")
+		stream := append(append([]byte{}, object[:header]...), 0xff, 0x01, 0x02)
+		stream = append(stream, object[header:]...)
+
+		d := NewDecoder(bytes.NewReader(stream), WithRecovery())
+		c, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, foo1, c)
+		assert.Equal(t, []Diagnostic{{
+			Offset:  int64(header + 1),
+			Path:    "[0]",
+			Err:     d.Diagnostics()[0].Err,
+			Skipped: 2,
+		}}, d.Diagnostics())
+		assert.EqualError(t, d.Diagnostics()[0].Err, "unexpected terminal constant: ff")
+		assert.Equal(t, "offset 5: [0]: unexpected terminal constant: ff (skipped 2 bytes)", d.Diagnostics()[0].String())
+	})
+
+	t.Run("complete", func(t *testing.T) {
+		d := NewDecoder(bytes.NewReader(mustReadFile("Node")), WithRecovery())
+		c, err := d.Decode()
+		assert.Nil(t, err)
+		assert.False(t, c.(Object).Incomplete)
+		assert.Nil(t, c.(Object).MissingFields())
+		assert.Nil(t, d.Diagnostics())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		d := NewDecoder(bytes.NewReader(mustReadFile("Node")[:130]))
+		c, err := d.Decode()
+		assert.Nil(t, c)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Nil(t, d.Diagnostics())
+	})
+}
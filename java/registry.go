@@ -0,0 +1,244 @@
+package java
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// A Registry maps Java class names to the Go types Unmarshal instantiates
+// when the target is an interface, such as a field of type any or a slice of
+// a Go interface type.
+type Registry struct {
+	mu       sync.RWMutex
+	types    map[string]reflect.Type
+	versions map[string]map[SerialVersionUID]reflect.Type
+	names    map[reflect.Type]string
+	uids     map[reflect.Type][]SerialVersionUID
+	enums    map[string]map[string]int64
+	convs    map[converterKey]converter
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		types:    make(map[string]reflect.Type),
+		versions: make(map[string]map[SerialVersionUID]reflect.Type),
+		names:    make(map[reflect.Type]string),
+		uids:     make(map[reflect.Type][]SerialVersionUID),
+		enums:    make(map[string]map[string]int64),
+		convs:    make(map[converterKey]converter),
+	}
+}
+
+// Register associates className with the dynamic type of v. Passing a
+// pointer (e.g. &Circle{}) registers the pointer type. The registration also
+// declares className as the Java class of v's type (see ClassNamer).
+func (r *Registry) Register(className string, v any) {
+	if v == nil {
+		panic("java: Register with nil value for " + className)
+	}
+	t := reflect.TypeOf(v)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types[className] = t
+	r.names[indirectType(t)] = className
+}
+
+// RegisterVersion associates one serialVersionUID of className with the
+// dynamic type of v. Types registered this way take precedence over those
+// registered with Register, and only accept data with a matching
+// serialVersionUID. Registering several versions lets historic data be
+// decoded after a Java class changed incompatibly.
+func (r *Registry) RegisterVersion(className string, uid SerialVersionUID, v any) {
+	if v == nil {
+		panic("java: RegisterVersion with nil value for " + className)
+	}
+	t := reflect.TypeOf(v)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.versions[className] == nil {
+		r.versions[className] = make(map[SerialVersionUID]reflect.Type)
+	}
+	r.versions[className][uid] = t
+	r.names[indirectType(t)] = className
+	r.uids[indirectType(t)] = append(r.uids[indirectType(t)], uid)
+}
+
+// SerialVersionUIDsFor returns the serialVersionUIDs goType was registered
+// for with RegisterVersion.
+func (r *Registry) SerialVersionUIDsFor(goType reflect.Type) ([]SerialVersionUID, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	uids, ok := r.uids[indirectType(goType)]
+	return uids, ok
+}
+
+// ClassNameFor returns the Java class name registered for goType.
+func (r *Registry) ClassNameFor(goType reflect.Type) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.names[indirectType(goType)]
+	return name, ok
+}
+
+// TypeFor returns the type registered for classDesc's class and
+// serialVersionUID, or for its class alone, or for the nearest superclass
+// that has one.
+func (r *Registry) TypeFor(classDesc *Class) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for cd := classDesc; cd != nil; cd = cd.Info.SuperClassDesc {
+		if t, ok := r.versions[cd.ClassName][cd.SerialVersionUID]; ok {
+			return t, true
+		}
+		if t, ok := r.types[cd.ClassName]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// RegisterEnum associates the constants of the Java enum className with the
+// integer values Unmarshal stores when the target is an integer type.
+func (r *Registry) RegisterEnum(className string, constants map[string]int64) {
+	table := make(map[string]int64, len(constants))
+	for k, v := range constants {
+		table[k] = v
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enums[className] = table
+}
+
+// EnumConstants returns the constant table registered for the enum class
+// described by classDesc or one of its superclasses. (Enum constants with a
+// body are instances of an anonymous subclass.)
+func (r *Registry) EnumConstants(classDesc *Class) (map[string]int64, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for cd := classDesc; cd != nil; cd = cd.Info.SuperClassDesc {
+		if t, ok := r.enums[cd.ClassName]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Unmarshal is like the package-level Unmarshal, but consults r before the
+// default registry when instantiating interface values.
+func (r *Registry) Unmarshal(data []byte, v any) error {
+	return UnmarshalWithOptions(data, v, WithRegistry(r))
+}
+
+func (r *Registry) UnmarshalReader(rd io.Reader, v any) error {
+	return UnmarshalReaderWithOptions(rd, v, WithRegistry(r))
+}
+
+var DefaultRegistry = NewRegistry()
+
+func RegisterType(className string, v any) {
+	DefaultRegistry.Register(className, v)
+}
+
+func RegisterVersion(className string, uid SerialVersionUID, v any) {
+	DefaultRegistry.RegisterVersion(className, uid, v)
+}
+
+func RegisterEnum(className string, constants map[string]int64) {
+	DefaultRegistry.RegisterEnum(className, constants)
+}
+
+func (u *unmarshaler) registeredType(classDesc *Class) (reflect.Type, bool) {
+	if u.opts.registry != nil {
+		if t, ok := u.opts.registry.TypeFor(classDesc); ok {
+			return t, true
+		}
+	}
+	return DefaultRegistry.TypeFor(classDesc)
+}
+
+func (u *unmarshaler) registeredClassName(goType reflect.Type) (string, bool) {
+	if u.opts.registry != nil {
+		if name, ok := u.opts.registry.ClassNameFor(goType); ok {
+			return name, true
+		}
+	}
+	return DefaultRegistry.ClassNameFor(goType)
+}
+
+func (u *unmarshaler) registeredSerialVersionUIDs(goType reflect.Type) ([]SerialVersionUID, bool) {
+	if u.opts.registry != nil {
+		if uids, ok := u.opts.registry.SerialVersionUIDsFor(goType); ok {
+			return uids, true
+		}
+	}
+	return DefaultRegistry.SerialVersionUIDsFor(goType)
+}
+
+func (u *unmarshaler) enumConstants(classDesc *Class) (map[string]int64, bool) {
+	if u.opts.registry != nil {
+		if t, ok := u.opts.registry.EnumConstants(classDesc); ok {
+			return t, true
+		}
+	}
+	return DefaultRegistry.EnumConstants(classDesc)
+}
+
+// unmarshalInterface fills an interface-typed value with a new instance of
+// the Go type registered for the Java value's class.
+func (u *unmarshaler) unmarshalInterface(javaValue Content, goValue reflect.Value) error {
+	if javaValue == nil {
+		return nil
+	}
+	t, ok := u.registeredType(classDescOf(javaValue))
+	if !ok {
+		if goValue.NumMethod() != 0 {
+			return fmt.Errorf("no registered type for %s", describe(javaValue))
+		}
+		if g := u.toGeneric(javaValue); g != nil {
+			goValue.Set(reflect.ValueOf(g))
+		}
+		return nil
+	}
+
+	v := reflect.New(reflect.PointerTo(t)).Elem()
+	if err := u.unmarshalValue(javaValue, v); err != nil {
+		return err
+	}
+	switch {
+	case t.AssignableTo(goValue.Type()):
+		goValue.Set(v.Elem())
+	case v.Type().AssignableTo(goValue.Type()):
+		goValue.Set(v)
+	default:
+		return fmt.Errorf("registered type %s does not implement %s", t, goValue.Type())
+	}
+	return nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func classDescOf(javaValue Content) *Class {
+	switch v := javaValue.(type) {
+	case Object:
+		return v.ClassDesc
+	case Enum:
+		return v.ClassDesc
+	case Array:
+		return v.ClassDesc
+	}
+	return nil
+}
+
+func describe(javaValue Content) string {
+	if cd := classDescOf(javaValue); cd != nil {
+		return cd.ClassName
+	}
+	return fmt.Sprintf("%T", javaValue)
+}
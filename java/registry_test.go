@@ -0,0 +1,126 @@
+package java
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Unmarshal(t *testing.T) {
+	r := NewRegistry()
+	r.Register("com.acme.Circle", circle{})
+	r.Register("com.acme.Square", &square{})
+
+	t.Run("interface slice", func(t *testing.T) {
+		var actual []shape
+		err := newUnmarshaler(WithRegistry(r)).unmarshalValue(shapeList, reflect.ValueOf(&actual))
+		assert.Nil(t, err)
+		assert.Equal(t, []shape{circle{Radius: 2}, &square{Side: 3}}, actual)
+	})
+
+	t.Run("superclass fallback", func(t *testing.T) {
+		var actual shape
+		err := newUnmarshaler(WithRegistry(r)).unmarshalValue(bigCircle, reflect.ValueOf(&actual))
+		assert.Nil(t, err)
+		assert.Equal(t, circle{Radius: 100}, actual)
+	})
+
+	t.Run("any", func(t *testing.T) {
+		var actual any
+		err := newUnmarshaler(WithRegistry(r)).unmarshalValue(bigCircle, reflect.ValueOf(&actual))
+		assert.Nil(t, err)
+		assert.Equal(t, circle{Radius: 100}, actual)
+	})
+
+	t.Run("unregistered class as any", func(t *testing.T) {
+		var actual any
+		err := newUnmarshaler().unmarshalValue(bigCircle, reflect.ValueOf(&actual))
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]any{"radius": 100}, actual)
+	})
+
+	t.Run("unregistered class as interface", func(t *testing.T) {
+		var actual shape
+		err := newUnmarshaler().unmarshalValue(bigCircle, reflect.ValueOf(&actual))
+		assert.Equal(t, "no registered type for com.acme.BigCircle", err.Error())
+	})
+
+	t.Run("type does not implement interface", func(t *testing.T) {
+		r := NewRegistry()
+		r.Register("com.acme.Circle", 0)
+		var actual shape
+		err := newUnmarshaler(WithRegistry(r)).unmarshalValue(bigCircle, reflect.ValueOf(&actual))
+		assert.Equal(t, "cannot cast com.acme.BigCircle to int", err.Error())
+	})
+
+	t.Run("default registry", func(t *testing.T) {
+		defer func(r *Registry) { DefaultRegistry = r }(DefaultRegistry)
+		DefaultRegistry = NewRegistry()
+		RegisterType("com.acme.Square", square{})
+
+		var actual []shape
+		err := newUnmarshaler(WithRegistry(r)).unmarshalValue(shapeList, reflect.ValueOf(&actual))
+		assert.Nil(t, err)
+		assert.Equal(t, []shape{circle{Radius: 2}, &square{Side: 3}}, actual, "per-call registry takes precedence")
+
+		actual = nil
+		err = newUnmarshaler().unmarshalValue(Array{Values: []Value{shapeList.Values[1]}}, reflect.ValueOf(&actual))
+		assert.Nil(t, err)
+		assert.Equal(t, []shape{square{Side: 3}}, actual)
+	})
+}
+
+type shape interface {
+	Area() float64
+}
+
+type circle struct {
+	Radius int `java:"com.acme.Circle.radius"`
+}
+
+func (c circle) Area() float64 {
+	return 3.14159 * float64(c.Radius*c.Radius)
+}
+
+type square struct {
+	Side int `java:"com.acme.Square.side"`
+}
+
+func (s square) Area() float64 {
+	return float64(s.Side * s.Side)
+}
+
+var comAcmeCircle = Class{
+	ClassName: "com.acme.Circle",
+	Info: ClassDescInfo{
+		Flags:  0x02,
+		Fields: []Field{{TypeInteger, "radius", ""}},
+	},
+}
+
+var comAcmeBigCircle = Class{
+	ClassName: "com.acme.BigCircle",
+	Info:      ClassDescInfo{Flags: 0x02, SuperClassDesc: &comAcmeCircle},
+}
+
+var comAcmeSquare = Class{
+	ClassName: "com.acme.Square",
+	Info: ClassDescInfo{
+		Flags:  0x02,
+		Fields: []Field{{TypeInteger, "side", ""}},
+	},
+}
+
+var bigCircle = Object{
+	ClassDesc: &comAcmeBigCircle,
+	ClassData: ClassData{"com.acme.Circle": {"radius": 100}, "com.acme.BigCircle": {}},
+}
+
+var shapeList = Array{
+	ClassDesc: &Class{ClassName: "[Lcom.acme.Shape;"},
+	Values: []Value{
+		Object{ClassDesc: &comAcmeCircle, ClassData: ClassData{"com.acme.Circle": {"radius": 2}}},
+		Object{ClassDesc: &comAcmeSquare, ClassData: ClassData{"com.acme.Square": {"side": 3}}},
+	},
+}
@@ -0,0 +1,153 @@
+package java
+
+import "strconv"
+
+// A Span records where an element was found in the stream. Decoders record
+// spans when created with WithSpans.
+type Span struct {
+	// Path locates the element in the decoded contents, in the form used by
+	// UnmarshalTypeError.JavaPath, e.g. "[0]/com.acme.Widget.parts[1]".
+	// Elements that only appear in the stream have bracketed names:
+	// "[class]" for an element's class descriptor, "[super]" for a
+	// superclass descriptor, "[annotation]" for class annotations and
+	// "[constant]" for the name of an enum constant. Fields of a class
+	// descriptor are named after the field.
+	Path string
+	Kind SpanKind
+	// Start and End are the offsets of the element's first byte and of the
+	// byte following it, as reported by Decoder.InputOffset.
+	Start, End int64
+	// Handle is the handle assigned to the element, or the handle that a
+	// reference refers to. It is -1 for elements without handles.
+	Handle int
+}
+
+type SpanKind int
+
+const (
+	SpanObject SpanKind = iota + 1
+	SpanArray
+	SpanEnum
+	SpanClass
+	SpanClassDesc
+	SpanString
+	SpanBlockData
+	SpanReference
+	SpanNull
+	SpanException
+	SpanValue // a primitive field; primitive array elements are not recorded separately
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanObject:
+		return "object"
+	case SpanArray:
+		return "array"
+	case SpanEnum:
+		return "enum"
+	case SpanClass:
+		return "class"
+	case SpanClassDesc:
+		return "classDesc"
+	case SpanString:
+		return "string"
+	case SpanBlockData:
+		return "blockData"
+	case SpanReference:
+		return "reference"
+	case SpanNull:
+		return "null"
+	case SpanException:
+		return "exception"
+	case SpanValue:
+		return "value"
+	}
+	return "unknown"
+}
+
+var spanKinds = map[TypeCode]SpanKind{
+	tcObject:         SpanObject,
+	tcClass:          SpanClass,
+	tcArray:          SpanArray,
+	tcString:         SpanString,
+	tcLongString:     SpanString,
+	tcEnum:           SpanEnum,
+	tcClassDesc:      SpanClassDesc,
+	tcProxyClassDesc: SpanClassDesc,
+	tcReference:      SpanReference,
+	tcNull:           SpanNull,
+	tcException:      SpanException,
+	tcBlockData:      SpanBlockData,
+	tcBlockDataLong:  SpanBlockData,
+}
+
+// Spans returns the spans of the elements decoded so far, in the order in
+// which they start. It returns nil unless the decoder was created with
+// WithSpans.
+func (d *Decoder) Spans() []Span {
+	return d.spans
+}
+
+// openSpan starts recording an element that begins at start, and returns a
+// value to pass to closeSpan.
+func (d *Decoder) openSpan(kind SpanKind, start int64) int {
+	if !d.opts.recordSpans {
+		return -1
+	}
+	d.spans = append(d.spans, Span{Path: joinPath(d.path, "/"), Kind: kind, Start: start, Handle: -1})
+	d.open = append(d.open, len(d.spans)-1)
+	return len(d.spans) - 1
+}
+
+func (d *Decoder) closeSpan(i int) {
+	if i < 0 {
+		return
+	}
+	d.spans[i].End = d.r.n
+	d.open = d.open[:len(d.open)-1]
+}
+
+// setSpanHandle records h for the innermost element being read, unless it
+// already has a handle.
+func (d *Decoder) setSpanHandle(h handle) {
+	if len(d.open) == 0 {
+		return
+	}
+	if s := &d.spans[d.open[len(d.open)-1]]; s.Handle < 0 {
+		s.Handle = int(h)
+	}
+}
+
+// tracking reports whether the decoder maintains the path of the element being
+// read, which is needed for spans and diagnostics.
+func (d *Decoder) tracking() bool {
+	return d.opts.recordSpans || d.opts.recover
+}
+
+// pushPath, pushIndex and pushField add an element to the path; popPath
+// removes it. pushIndex and pushField only build the element if the path is
+// used.
+func (d *Decoder) pushPath(elem string) {
+	if d.tracking() {
+		d.path = append(d.path, elem)
+	}
+}
+
+func (d *Decoder) pushIndex(i int) {
+	if d.tracking() {
+		d.path = append(d.path, "["+strconv.Itoa(i)+"]")
+	}
+}
+
+func (d *Decoder) pushField(className, fieldName string) {
+	if d.tracking() {
+		d.path = append(d.path, className+"."+fieldName)
+	}
+}
+
+func (d *Decoder) popPath() {
+	if d.tracking() {
+		d.path = d.path[:len(d.path)-1]
+	}
+}
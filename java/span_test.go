@@ -0,0 +1,78 @@
+package java
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoder_Spans(t *testing.T) {
+	t.Run("references", func(t *testing.T) {
+		d := NewDecoder(bytes.NewReader(mustReadFile("Node")), WithSpans())
+		_, err := d.Decode()
+		assert.Nil(t, err)
+
+		const next, name, parent = "/com.edutko.Main$Node.next", "/com.edutko.Main$Node.name", "/com.edutko.Main$Node.parent"
+		assert.Equal(t, []Span{
+			{"[0]", SpanObject, 4, 154, 0x7e0003},
+			{"[0][class]", SpanClassDesc, 5, 115, 0x7e0000},
+			{"[0][class]/name", SpanString, 46, 67, 0x7e0001},
+			{"[0][class]/next", SpanString, 74, 99, 0x7e0002},
+			{"[0][class]/parent", SpanReference, 108, 113, 0x7e0002},
+			{"[0]" + name, SpanString, 115, 122, 0x7e0004},
+			{"[0]" + next, SpanObject, 122, 153, 0x7e0005},
+			{"[0]" + next + "[class]", SpanReference, 123, 128, 0x7e0000},
+			{"[0]" + next + name, SpanString, 128, 132, 0x7e0006},
+			{"[0]" + next + next, SpanObject, 132, 148, 0x7e0007},
+			{"[0]" + next + next + "[class]", SpanReference, 133, 138, 0x7e0000},
+			{"[0]" + next + next + name, SpanString, 138, 142, 0x7e0008},
+			{"[0]" + next + next + next, SpanNull, 142, 143, -1},
+			{"[0]" + next + next + parent, SpanReference, 143, 148, 0x7e0003},
+			{"[0]" + next + parent, SpanReference, 148, 153, 0x7e0003},
+			{"[0]" + parent, SpanNull, 153, 154, -1},
+		}, d.Spans())
+	})
+
+	t.Run("annotations", func(t *testing.T) {
+		d := NewDecoder(bytes.NewReader(mustReadFile("ArrayList")), WithSpans())
+		_, err := d.Decode()
+		assert.Nil(t, err)
+
+		const annotation = "[0]/java.util.ArrayList.[object annotation]"
+		spans := d.Spans()
+		assert.Equal(t, Span{"[0]/java.util.ArrayList.size", SpanValue, 47, 51, -1}, spans[2])
+		assert.Equal(t, Span{annotation + "[0]", SpanBlockData, 51, 57, -1}, spans[3])
+		assert.Equal(t, Span{annotation + "[1][class][super]", SpanClassDesc, 98, 130, 0x7e0003}, spans[6])
+		assert.Equal(t, Span{annotation + "[4]/java.lang.Integer.value", SpanValue, 160, 164, -1}, spans[len(spans)-1])
+	})
+
+	t.Run("multiple elements", func(t *testing.T) {
+		header := len(StreamMagic) + 2
+		str, enum := mustReadFile("string"), mustReadFile("enum")
+		d := NewDecoder(bytes.NewReader(append(append([]byte{}, str...), enum[header:]...)), WithSpans())
+		_, err := d.DecodeAll(2)
+		assert.Nil(t, err)
+
+		spans := d.Spans()
+		assert.Equal(t, Span{"[0]", SpanString, 4, int64(len(str)), 0x7e0000}, spans[0])
+		assert.Equal(t, "[1]", spans[1].Path)
+		assert.Equal(t, SpanEnum, spans[1].Kind)
+		assert.Equal(t, Span{"[1][constant]", SpanString, spans[len(spans)-1].Start, spans[1].End, spans[1].Handle + 1}, spans[len(spans)-1])
+	})
+
+	t.Run("primitive array", func(t *testing.T) {
+		d := NewDecoder(bytes.NewReader(mustReadFile("ints")), WithSpans())
+		_, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Len(t, d.Spans(), 2)
+		assert.Equal(t, Span{"[0]", SpanArray, 4, 39, 0x7e0001}, d.Spans()[0])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		d := NewDecoder(bytes.NewReader(mustReadFile("Node")))
+		_, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Nil(t, d.Spans())
+	})
+}
@@ -3,37 +3,228 @@ package java
 import (
 	"fmt"
 	"strings"
+	"unicode/utf16"
 )
 
 type Annotation any
 
+// Array holds the elements of an object array in Values. The elements of a
+// primitive array are instead held in Data as a typed slice: []byte for
+// byte[], []uint16 for char[], []float64, []float32, []int32, []int64, []int16
+// or []bool.
+//
+// When decoding with WithZeroCopy, the elements of a primitive array are held
+// in Raw as they appear in the input, big-endian, and Data is only set for
+// byte[], to the same slice. The methods below decode Raw as needed.
 type Array struct {
 	ClassDesc *Class
 	Values    []Value
+	Data      any
+	Raw       []byte
+	// Incomplete is set when decoding with WithRecovery and the array's
+	// values could not all be read.
+	Incomplete bool
+
+	// rawType is the element type of Raw, set by the decoder so that it is
+	// not parsed from the class name on every access.
+	rawType TypeCode
+}
+
+// data returns Data, decoding Raw if necessary.
+func (a Array) data() any {
+	if a.Data != nil || a.Raw == nil {
+		return a.Data
+	}
+	return decodePrimitives(a.rawItemType(), a.Raw)
+}
+
+func (a Array) rawItemType() TypeCode {
+	if a.rawType != 0 {
+		return a.rawType
+	}
+	t, _ := a.ItemType()
+	return t
 }
 
 func (a Array) Length() int {
+	if a.Data == nil && a.Raw != nil {
+		return len(a.Raw) / int(primitiveSizes[a.rawItemType()])
+	}
+	switch d := a.Data.(type) {
+	case []byte:
+		return len(d)
+	case []uint16:
+		return len(d)
+	case []float64:
+		return len(d)
+	case []float32:
+		return len(d)
+	case []int32:
+		return len(d)
+	case []int64:
+		return len(d)
+	case []int16:
+		return len(d)
+	case []bool:
+		return len(d)
+	}
 	return len(a.Values)
 }
 
+// Get returns the element at index. Elements of primitive arrays are returned
+// as the same types that Decoder uses for primitive fields.
 func (a Array) Get(index int) Value {
+	if a.Data == nil && a.Raw != nil {
+		t := a.rawItemType()
+		size := int(primitiveSizes[t])
+		return Array{Data: decodePrimitives(t, a.Raw[index*size:(index+1)*size])}.Get(0)
+	}
+	switch d := a.Data.(type) {
+	case []byte:
+		return int8(d[index])
+	case []uint16:
+		return utf16.Decode(d[index : index+1])[0]
+	case []float64:
+		return d[index]
+	case []float32:
+		return d[index]
+	case []int32:
+		return int(d[index])
+	case []int64:
+		return d[index]
+	case []int16:
+		return d[index]
+	case []bool:
+		return d[index]
+	}
 	return a.Values[index]
 }
 
+// Bytes returns the contents of a byte[].
+func (a Array) Bytes() ([]byte, bool) {
+	d, ok := a.data().([]byte)
+	return d, ok
+}
+
+// Chars returns the UTF-16 code units of a char[].
+func (a Array) Chars() ([]uint16, bool) {
+	d, ok := a.data().([]uint16)
+	return d, ok
+}
+
+// Doubles returns the contents of a double[].
+func (a Array) Doubles() ([]float64, bool) {
+	d, ok := a.data().([]float64)
+	return d, ok
+}
+
+// Floats returns the contents of a float[].
+func (a Array) Floats() ([]float32, bool) {
+	d, ok := a.data().([]float32)
+	return d, ok
+}
+
+// Ints returns the contents of an int[].
+func (a Array) Ints() ([]int32, bool) {
+	d, ok := a.data().([]int32)
+	return d, ok
+}
+
+// Longs returns the contents of a long[].
+func (a Array) Longs() ([]int64, bool) {
+	d, ok := a.data().([]int64)
+	return d, ok
+}
+
+// Shorts returns the contents of a short[].
+func (a Array) Shorts() ([]int16, bool) {
+	d, ok := a.data().([]int16)
+	return d, ok
+}
+
+// Booleans returns the contents of a boolean[].
+func (a Array) Booleans() ([]bool, bool) {
+	d, ok := a.data().([]bool)
+	return d, ok
+}
+
+// ItemType returns the type code of the array's components and the rest of
+// the array's class name after the leading "[", without a trailing ";": "I"
+// for int[], "Ljava.lang.String" for String[] and "[I" for int[][]. Use
+// ItemClassName for the components' class name.
 func (a Array) ItemType() (TypeCode, string) {
-	className := a.ClassDesc.ClassName
-	if len(className) < 2 {
+	if a.ClassDesc == nil && a.rawType != 0 {
+		return a.rawType, string(a.rawType)
+	}
+	c, err := a.ComponentType()
+	if err != nil {
+		return 0, ""
+	}
+	return c.Code, strings.TrimSuffix(a.ClassDesc.ClassName[1:], ";")
+}
+
+// ItemClassName returns the name of the class of the array's components, as
+// Java's Class.getName would: "int" for int[], "java.lang.String" for String[]
+// and "[I" for int[][].
+func (a Array) ItemClassName() string {
+	if a.ClassDesc == nil && a.rawType != 0 {
+		return primitiveNames[a.rawType]
+	}
+	c, err := a.ComponentType()
+	if err != nil {
+		return ""
+	}
+	return c.BinaryName()
+}
+
+// ElementType is like ItemType, but looks through all dimensions of the
+// array and names the element class as ItemClassName does: it returns
+// TypeInteger and "int" for int[][].
+func (a Array) ElementType() (TypeCode, string) {
+	c, err := a.ComponentType()
+	if err != nil {
 		return 0, ""
 	}
-	return TypeCode(className[1]), strings.TrimSuffix(strings.TrimPrefix(className, "["), ";")
+	e := c.ElementType()
+	return e.Code, e.BinaryName()
+}
+
+// ComponentType returns the type of the array's components.
+func (a Array) ComponentType() (JavaType, error) {
+	t, err := a.ClassDesc.Type()
+	if err != nil {
+		return JavaType{}, err
+	}
+	if !t.IsArray() {
+		return JavaType{}, fmt.Errorf("%s is not an array class", t.BinaryName())
+	}
+	return *t.Component, nil
+}
+
+// Dimensions returns the number of dimensions of the array's type.
+func (a Array) Dimensions() int {
+	t, _ := a.ClassDesc.Type()
+	return t.Dimensions()
 }
 
+// BlockData holds primitive data written by a class's writeObject or
+// writeExternal method.
+type BlockData []byte
+
 type Class struct {
 	ClassName        string
 	SerialVersionUID SerialVersionUID
 	Info             ClassDescInfo
 }
 
+// Type returns the class's type, parsed from its name.
+func (c *Class) Type() (JavaType, error) {
+	if c == nil {
+		return JavaType{}, fmt.Errorf("no class descriptor")
+	}
+	return ParseClassName(c.ClassName)
+}
+
 type ClassData map[string]map[string]Value
 type ClassDescFlags byte
 
@@ -73,9 +264,23 @@ type Field struct {
 	ClassName string
 }
 
+// Type returns the field's type, parsed from its descriptor.
+func (f Field) Type() (JavaType, error) {
+	if f.TypeCode.IsPrimitive() {
+		if _, ok := primitiveNames[f.TypeCode]; !ok {
+			return JavaType{}, fmt.Errorf("unknown type code: '%c'", f.TypeCode)
+		}
+		return JavaType{Code: f.TypeCode}, nil
+	}
+	return ParseDescriptor(f.ClassName)
+}
+
 type Object struct {
 	ClassDesc *Class
 	ClassData ClassData
+	// Incomplete is set when decoding with WithRecovery and the object's
+	// fields could not all be read; see MissingFields.
+	Incomplete bool
 }
 
 func (o Object) GetClassName() string {
@@ -85,6 +290,49 @@ func (o Object) GetClassName() string {
 	return o.ClassDesc.ClassName
 }
 
+// IsInstanceOf reports whether className is the object's class or one of its
+// superclasses.
+func (o Object) IsInstanceOf(className string) bool {
+	for cd := o.ClassDesc; cd != nil; cd = cd.Info.SuperClassDesc {
+		if cd.ClassName == className {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAnnotation returns the contents written by className's writeObject
+// method, if any.
+func (o Object) GetAnnotation(className string) []Annotation {
+	a, _ := o.ClassData[className][objectAnnotationKey].([]Annotation)
+	return a
+}
+
+// MissingFields returns the qualified names of fields declared by the
+// object's classes that are absent from its class data, as happens for
+// incomplete objects.
+func (o Object) MissingFields() []string {
+	var missing []string
+	for _, cd := range classHierarchy(o.ClassDesc) {
+		for _, f := range cd.Info.Fields {
+			if _, ok := o.ClassData[cd.ClassName][f.FieldName]; !ok {
+				missing = append(missing, cd.ClassName+"."+f.FieldName)
+			}
+		}
+	}
+	return missing
+}
+
+// classHierarchy returns the class descriptors of classDesc and its
+// superclasses, root superclass first.
+func classHierarchy(classDesc *Class) []*Class {
+	var classDescs []*Class
+	for cd := classDesc; cd != nil; cd = cd.Info.SuperClassDesc {
+		classDescs = append([]*Class{cd}, classDescs...)
+	}
+	return classDescs
+}
+
 func (o Object) GetField(name string) (any, error) {
 	parts := strings.Split(name, ".")
 	last := len(parts) - 1
@@ -0,0 +1,63 @@
+package java
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArray_primitives(t *testing.T) {
+	ints := Array{ClassDesc: &intArray, Data: []int32{1, -2, 3}}
+	assert.Equal(t, 3, ints.Length())
+	assert.Equal(t, -2, ints.Get(1))
+	v, ok := ints.Ints()
+	assert.True(t, ok)
+	assert.Equal(t, []int32{1, -2, 3}, v)
+	_, ok = ints.Longs()
+	assert.False(t, ok)
+
+	bs := Array{ClassDesc: &byteArray, Data: []byte{0xff}}
+	assert.Equal(t, int8(-1), bs.Get(0))
+
+	chars := Array{ClassDesc: &charArray, Data: []uint16{'a'}}
+	assert.Equal(t, 'a', chars.Get(0))
+
+	objects := Array{ClassDesc: &stringArray, Values: []Value{"a", "b"}}
+	assert.Equal(t, 2, objects.Length())
+	_, ok = objects.Bytes()
+	assert.False(t, ok)
+}
+
+func TestArray_ItemType(t *testing.T) {
+	testCases := []struct {
+		className     string
+		itemType      TypeCode
+		itemName      string
+		itemClassName string
+		elementType   TypeCode
+		elementName   string
+		dimensions    int
+	}{
+		{"[I", TypeInteger, "I", "int", TypeInteger, "int", 1},
+		{"[Z", TypeBoolean, "Z", "boolean", TypeBoolean, "boolean", 1},
+		{"[Ljava.lang.String;", TypeObject, "Ljava.lang.String", "java.lang.String", TypeObject, "java.lang.String", 1},
+		{"[[I", TypeArray, "[I", "[I", TypeInteger, "int", 2},
+		{"[[Ljava.lang.String;", TypeArray, "[Ljava.lang.String", "[Ljava.lang.String;", TypeObject, "java.lang.String", 2},
+		{"[[[Lcom.example.Outer$Inner;", TypeArray, "[[Lcom.example.Outer$Inner", "[[Lcom.example.Outer$Inner;", TypeObject, "com.example.Outer$Inner", 3},
+		{"java.lang.String", 0, "", "", 0, "", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.className, func(t *testing.T) {
+			a := Array{ClassDesc: &Class{ClassName: tc.className}}
+			itemType, itemName := a.ItemType()
+			assert.Equal(t, tc.itemType, itemType)
+			assert.Equal(t, tc.itemName, itemName)
+			assert.Equal(t, tc.itemClassName, a.ItemClassName())
+			elementType, elementName := a.ElementType()
+			assert.Equal(t, tc.elementType, elementType)
+			assert.Equal(t, tc.elementName, elementName)
+			assert.Equal(t, tc.dimensions, a.Dimensions())
+		})
+	}
+}
@@ -1,38 +1,160 @@
 package java
 
 import (
-	"bytes"
+	"context"
+	"encoding"
+	"errors"
 	"fmt"
 	"io"
 	"path"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf16"
 )
 
-func AddPackagePrefixes(prefixes ...string) {
-	packagePrefixes = append(packagePrefixes, prefixes...)
+// Unmarshal decodes the first element of a serialized Java stream into v.
+//
+// Struct fields are mapped using "java" tags of the form
+// "[className.]fieldName[,option...]". A field name without a class refers to
+// the class declared for (or derived from) the struct type. The "-" tag skips
+// a field. By default every tagged field must exist in the object; the
+// "optional" option leaves the field untouched when it does not, and
+// "required" states the default explicitly. Other options are rejected.
+//
+// A java.util.HashMap is unmarshaled into a Go map by unmarshaling each of its
+// keys and values into the map's key and element types. Other objects can be
+// unmarshaled into a map with string keys, which receives their fields.
+//
+// Pointers to the same Java object receive the same Go pointer, so shared
+// references and cycles are preserved.
+func Unmarshal(data []byte, v any) error {
+	u := newUnmarshaler()
+	return u.unmarshalNext(newBytesDecoder(data, u.opts), v)
 }
 
-func SetPackagePrefixes(prefixes ...string) {
-	packagePrefixes = prefixes
+// UnmarshalContext is like UnmarshalWithOptions, but gives up with ctx.Err()
+// once ctx is done; see Decoder.DecodeContext.
+func UnmarshalContext(ctx context.Context, data []byte, v any, opts ...Option) error {
+	u := newUnmarshaler(opts...)
+	d := newBytesDecoder(data, u.opts)
+	d.ctx = ctx
+	return u.unmarshalNext(d, v)
 }
 
-func Unmarshal(data []byte, v any) error {
-	return UnmarshalReader(bytes.NewReader(data), v)
+func UnmarshalReader(r io.Reader, v any) error {
+	return newUnmarshaler().unmarshal(r, v)
 }
 
-func UnmarshalReader(r io.Reader, v any) error {
+// UnmarshalWithOptions is like Unmarshal, but applies opts on top of the
+// package defaults.
+func UnmarshalWithOptions(data []byte, v any, opts ...Option) error {
+	u := newUnmarshaler(opts...)
+	return u.unmarshalNext(newBytesDecoder(data, u.opts), v)
+}
+
+func UnmarshalReaderWithOptions(r io.Reader, v any, opts ...Option) error {
+	u := newUnmarshaler(opts...)
+	return u.unmarshalNext(newDecoder(r, u.opts), v)
+}
+
+// UnmarshalContent stores already-decoded content in the value pointed to by
+// v. Unmarshaler implementations should use UnmarshalState.Unmarshal instead,
+// which keeps the options, references and paths of the enclosing call.
+func UnmarshalContent(c Content, v any, opts ...Option) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	u := newUnmarshaler(opts...)
+	if err := u.unmarshalValue(c, rv); err != nil {
+		return err
+	}
+	return errors.Join(u.errs...)
+}
+
+// Unmarshaler is implemented by types that decode themselves from Java
+// content. The content is an Object, Array, Enum, string, primitive value or
+// nil, exactly as produced by Decoder. s decodes parts of the content as the
+// enclosing call would.
+type Unmarshaler interface {
+	UnmarshalJava(c Content, s *UnmarshalState) error
+}
+
+// An UnmarshalState is passed to Unmarshaler implementations so that the
+// parts of the content they delegate are decoded with the options, registry
+// and shared references of the enclosing call, and that errors report where
+// they occurred.
+type UnmarshalState struct {
+	u *unmarshaler
+}
+
+// Unmarshal stores c in the value pointed to by v.
+func (s *UnmarshalState) Unmarshal(c Content, v any) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Pointer || rv.IsNil() {
 		return &InvalidUnmarshalError{reflect.TypeOf(v)}
 	}
+	return s.u.unmarshalValue(c, rv)
+}
+
+// UnmarshalField is like Unmarshal, for the value of the Java field javaName
+// stored in the Go field goName. The names are added to the paths that errors
+// report.
+func (s *UnmarshalState) UnmarshalField(c Content, javaName, goName string, v any) error {
+	s.u.enter(javaName, goName)
+	defer s.u.leave()
+	return s.Unmarshal(c, v)
+}
+
+// TypeError returns an *UnmarshalTypeError for c, which cannot be stored in a
+// value of type goType, at the current path.
+func (s *UnmarshalState) TypeError(c Content, goType reflect.Type) error {
+	return s.u.typeError(c, goType)
+}
 
-	content, err := NewDecoder(r).Decode()
+type unmarshaler struct {
+	opts options
+
+	javaPath []string
+	goPath   []string
+	errs     []error
+
+	// refs maps decoded objects to the Go pointers they have been stored in,
+	// so that shared references and cycles are preserved. active holds the
+	// objects currently being stored in non-pointer values.
+	refs    map[refKey]reflect.Value
+	active  map[refKey]bool
+	generic map[identity]any
+	// mapValues counts the map values being unmarshalled, which are stored
+	// in temporary values and then copied into the map.
+	mapValues int
+}
+
+func newUnmarshaler(opts ...Option) *unmarshaler {
+	return &unmarshaler{opts: newOptions(opts)}
+}
+
+func (u *unmarshaler) unmarshal(r io.Reader, v any) error {
+	return u.unmarshalNext(newDecoder(r, u.opts), v)
+}
+
+func (u *unmarshaler) unmarshalNext(d *Decoder, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+
+	content, err := d.Decode()
 	if err != nil {
 		return fmt.Errorf("java.Decode: %w", err)
 	}
 
-	err = unmarshalValue(content, rv)
+	err = u.unmarshalValue(content, rv)
+	if err == nil && len(u.errs) > 0 {
+		err = errors.Join(u.errs...)
+	}
 	if err != nil {
 		return fmt.Errorf("unmarshalValue: %w", err)
 	}
@@ -40,165 +162,289 @@ func UnmarshalReader(r io.Reader, v any) error {
 	return nil
 }
 
-func unmarshalValue(javaValue Content, goValue reflect.Value) error {
+func (u *unmarshaler) unmarshalValue(javaValue Content, goValue reflect.Value) (err error) {
+	defer func() { err = u.locate(err) }()
+	if converted, err := u.convert(javaValue, goValue); converted {
+		return err
+	}
+	if um, ok := asUnmarshaler(goValue); ok {
+		return um.UnmarshalJava(javaValue, &UnmarshalState{u})
+	}
+	if e, ok := javaValue.(Enum); ok {
+		if handled, err := u.unmarshalEnum(e, goValue); handled {
+			return err
+		}
+	}
+	if id, ok := identityOf(javaValue); ok {
+		switch goValue.Kind() {
+		case reflect.Pointer, reflect.Interface:
+		default:
+			key := refKey{id, goValue.Type()}
+			if u.active[key] {
+				return fmt.Errorf("cyclic reference to %s cannot be stored in %s", describe(javaValue), goValue.Type())
+			}
+			if u.active == nil {
+				u.active = make(map[refKey]bool)
+			}
+			u.active[key] = true
+			defer delete(u.active, key)
+			// Pointers to the object can share the value it is stored in,
+			// unless that is a copy that is about to be stored in a map.
+			if goValue.CanAddr() && u.mapValues == 0 {
+				u.share(refKey{id, reflect.PointerTo(goValue.Type())}, goValue.Addr())
+			}
+		}
+	}
+
 	switch k := goValue.Kind(); k {
 	case reflect.Bool:
 		if v, ok := castToBool(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
-			return fmt.Errorf("cannot cast %T to bool", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Int:
 		if v, ok := castToInt(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
-			return fmt.Errorf("cannot cast %T to int", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Int8:
 		if v, ok := castToInt8(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
-			return fmt.Errorf("cannot cast %T to int8", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Int16:
 		if v, ok := castToInt16(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
-			return fmt.Errorf("cannot cast %T to int16", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Int32:
 		if v, ok := castToInt32(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
-			return fmt.Errorf("cannot cast %T to int32", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Int64:
 		if v, ok := castToInt64(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
-			return fmt.Errorf("cannot cast %T to int64", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Uint:
 		if v, ok := castToUint(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
-			return fmt.Errorf("cannot cast %T to uint", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Uint8:
-		if v, ok := castToUint8(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+		if v, ok := castToUint8(javaValue, u.opts.allowInt8ToByteCoercion); ok {
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
-			return fmt.Errorf("cannot cast %T to uint8", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Uint16:
 		if v, ok := castToUint16(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
 
-			return fmt.Errorf("cannot cast %T to uint16", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Uint32:
 		if v, ok := castToUint32(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
-			return fmt.Errorf("cannot cast %T to uint32", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Uint64:
 		if v, ok := castToUint64(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
-			return fmt.Errorf("cannot cast %T to uint64", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Float32:
 		if v, ok := castToFloat32(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
-			return fmt.Errorf("cannot cast %T to float32", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Float64:
 		if v, ok := castToFloat64(javaValue); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.Set(reflect.ValueOf(v).Convert(goValue.Type()))
 		} else {
-			return fmt.Errorf("cannot cast %T to float64", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Array:
+		if javaValue == nil {
+			return nil
+		}
 		if arr, ok := javaValue.(Array); ok {
-			if len(arr.Values) != goValue.Len() {
-				return fmt.Errorf("array size mismatch: expected %d, got %d", goValue.Len(), len(arr.Values))
+			if arr.Length() != goValue.Len() {
+				return u.collect(u.locate(fmt.Errorf("array size mismatch: expected %d, got %d", goValue.Len(), arr.Length())))
+			}
+			if u.copyPrimitiveArray(arr, goValue) {
+				return nil
 			}
 			for i := 0; i < arr.Length(); i++ {
-				err := unmarshalValue(arr.Get(i), goValue.Index(i))
-				if err != nil {
+				u.enterIndex(i)
+				err := u.unmarshalValue(arr.Get(i), goValue.Index(i))
+				u.leave()
+				if err = u.collect(err); err != nil {
 					return err
 				}
 			}
 		} else {
-			return fmt.Errorf("cannot cast %T to array", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Map:
-		// TODO: implement support for Java HashMap
-		// TODO: consider support for unmarshalling arbitrary objects as map[string]any
-		return ErrNotSupported
+		if javaValue == nil {
+			goValue.Set(reflect.Zero(goValue.Type()))
+			return nil
+		}
+		javaObj, ok := javaValue.(Object)
+		if !ok {
+			return u.typeError(javaValue, goValue.Type())
+		}
+		if isHashMap(javaObj) {
+			return u.unmarshalHashMap(javaObj, goValue)
+		}
+		if goValue.Type().Key().Kind() != reflect.String {
+			return ErrNotSupported
+		}
+		if goValue.IsNil() {
+			goValue.Set(reflect.MakeMap(goValue.Type()))
+		}
+		fields := mergedFields(javaObj)
+		for _, name := range sortedKeys(fields) {
+			itm := reflect.New(goValue.Type().Elem()).Elem()
+			u.enter(name, "["+strconv.Quote(name)+"]")
+			u.mapValues++
+			err := u.unmarshalValue(fields[name], itm)
+			u.mapValues--
+			u.leave()
+			if err = u.collect(err); err != nil {
+				return err
+			}
+			goValue.SetMapIndex(reflect.ValueOf(name).Convert(goValue.Type().Key()), itm)
+		}
 
 	case reflect.Pointer:
-		return unmarshalValue(javaValue, goValue.Elem())
+		id, shared := identityOf(javaValue)
+		key := refKey{id, goValue.Type()}
+		if p, ok := u.refs[key]; shared && ok && goValue.CanSet() {
+			goValue.Set(p)
+			return nil
+		}
+		if goValue.IsNil() {
+			if javaValue == nil {
+				return nil
+			}
+			goValue.Set(reflect.New(goValue.Type().Elem()))
+		}
+		if shared {
+			u.share(key, goValue)
+		}
+		// The value pointed to is not part of a copy stored in a map.
+		defer func(n int) { u.mapValues = n }(u.mapValues)
+		u.mapValues = 0
+		return u.unmarshalValue(javaValue, goValue.Elem())
 
 	case reflect.Slice:
+		if javaValue == nil {
+			goValue.Set(reflect.Zero(goValue.Type()))
+			return nil
+		}
+		var values []Value
 		if arr, ok := javaValue.(Array); ok {
-			for i := 0; i < arr.Length(); i++ {
-				itm := reflect.New(goValue.Type().Elem())
-				err := unmarshalValue(arr.Get(i), itm)
-				if err != nil {
-					return err
-				}
-				goValue.Set(reflect.Append(goValue, reflect.Indirect(itm)))
+			if goValue.IsNil() {
+				goValue.Set(reflect.MakeSlice(goValue.Type(), 0, arr.Length()))
+			}
+			if u.copyPrimitiveArray(arr, goValue) {
+				return nil
+			}
+			values = make([]Value, arr.Length())
+			for i := range values {
+				values[i] = arr.Get(i)
+			}
+		} else if obj, ok := javaValue.(Object); ok && isArrayList(obj) {
+			values = listElements(obj)
+			if goValue.IsNil() {
+				goValue.Set(reflect.MakeSlice(goValue.Type(), 0, len(values)))
 			}
 		} else {
-			return fmt.Errorf("cannot cast %T to slice", javaValue)
+			return u.typeError(javaValue, goValue.Type())
+		}
+		// The elements are stored in place, so that pointers to them can be
+		// shared.
+		start := goValue.Len()
+		goValue.Set(reflect.AppendSlice(goValue, reflect.MakeSlice(goValue.Type(), len(values), len(values))))
+		for i, v := range values {
+			u.enterIndex(i)
+			err := u.unmarshalValue(v, goValue.Index(start+i))
+			u.leave()
+			if err = u.collect(err); err != nil {
+				goValue.SetLen(start + i)
+				return err
+			}
 		}
 
 	case reflect.String:
 		if v, ok := javaValue.(string); ok {
-			goValue.Set(reflect.ValueOf(v))
+			goValue.SetString(v)
+		} else if chars, ok := charsOf(javaValue); ok {
+			goValue.SetString(string(utf16.Decode(chars)))
 		} else {
-			return fmt.Errorf("cannot cast %T to string", javaValue)
+			return u.typeError(javaValue, goValue.Type())
 		}
 
 	case reflect.Struct:
 		if javaObj, ok := javaValue.(Object); ok {
-			typ := goValue.Type()
-			for i := 0; i < typ.NumField(); i++ {
-				sf := typ.Field(i)
-				goField := goValue.FieldByName(sf.Name)
-				if !goField.CanSet() {
-					continue
-				}
-				javaField, err := getField(javaObj, typ, i)
-				if err != nil {
-					return fmt.Errorf("getField: %w", err)
-				}
-				err = unmarshalValue(javaField, goField)
-				if err != nil {
-					return err
-				}
+			className, declared := u.declaredClassName(goValue.Type())
+			if !declared {
+				className = u.classNameForType(goValue.Type())
+			} else if !javaObj.IsInstanceOf(className) {
+				return fmt.Errorf("%#v is not a %s: %w", javaObj.GetClassName(), className, ErrClassMismatch)
 			}
+			// A type that does not declare its class is checked against the
+			// object's own class.
+			versionClass := className
+			if !declared && !javaObj.IsInstanceOf(className) {
+				versionClass = javaObj.GetClassName()
+			}
+			if err := u.checkSerialVersionUID(javaObj, goValue.Type(), versionClass); err != nil {
+				return err
+			}
+			mapped := make(map[string]bool)
+			if err := u.unmarshalStruct(javaObj, goValue, className, mapped); err != nil {
+				return err
+			}
+			if u.opts.disallowUnknownFields {
+				return checkUnmappedFields(javaObj, mapped)
+			}
+		} else if javaValue != nil {
+			return u.typeError(javaValue, goValue.Type())
 		}
 
-	case reflect.Chan, reflect.Complex64, reflect.Complex128, reflect.Func, reflect.Interface, reflect.Invalid, reflect.Uintptr, reflect.UnsafePointer:
+	case reflect.Interface:
+		return u.unmarshalInterface(javaValue, goValue)
+
+	case reflect.Chan, reflect.Complex64, reflect.Complex128, reflect.Func, reflect.Invalid, reflect.Uintptr, reflect.UnsafePointer:
 		return ErrNotSupported
 
 	default:
@@ -208,35 +454,783 @@ func unmarshalValue(javaValue Content, goValue reflect.Value) error {
 	return nil
 }
 
-func getField(object Object, goType reflect.Type, index int) (Content, error) {
-	goField := goType.Field(index)
-	fieldName := goField.Tag.Get("java")
+// unmarshalEnum stores an enum constant in a string-kind value, a
+// TextUnmarshaler, or an integer value using the constant table registered
+// for the enum class. It returns false if goValue is none of these.
+func (u *unmarshaler) unmarshalEnum(e Enum, goValue reflect.Value) (bool, error) {
+	if tu, ok := asTextUnmarshaler(goValue); ok {
+		return true, tu.UnmarshalText([]byte(e.ConstantName))
+	}
+
+	switch goValue.Kind() {
+	case reflect.String:
+		goValue.SetString(e.ConstantName)
+		return true, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		className := ""
+		if e.ClassDesc != nil {
+			className = e.ClassDesc.ClassName
+		}
+		constants, ok := u.enumConstants(e.ClassDesc)
+		if !ok {
+			return true, fmt.Errorf("no constants registered for enum %s", className)
+		}
+		v, ok := constants[e.ConstantName]
+		if !ok {
+			return true, fmt.Errorf("unknown constant %#v for enum %s (valid constants: %s)",
+				e.ConstantName, className, strings.Join(sortedConstants(constants), ", "))
+		}
+		if goValue.CanInt() {
+			if goValue.OverflowInt(v) {
+				return true, fmt.Errorf("enum constant %s.%s (%d) overflows %s", className, e.ConstantName, v, goValue.Type())
+			}
+			goValue.SetInt(v)
+		} else {
+			if v < 0 || goValue.OverflowUint(uint64(v)) {
+				return true, fmt.Errorf("enum constant %s.%s (%d) overflows %s", className, e.ConstantName, v, goValue.Type())
+			}
+			goValue.SetUint(uint64(v))
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// sortedConstants returns the names in an enum constant table, ordered by
+// value.
+func sortedConstants(constants map[string]int64) []string {
+	names := make([]string, 0, len(constants))
+	for name := range constants {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if constants[names[i]] != constants[names[j]] {
+			return constants[names[i]] < constants[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func asTextUnmarshaler(goValue reflect.Value) (encoding.TextUnmarshaler, bool) {
+	switch goValue.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Invalid:
+		return nil, false
+	}
+	if goValue.CanAddr() && goValue.Addr().CanInterface() {
+		if tu, ok := goValue.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return tu, true
+		}
+	}
+	return nil, false
+}
+
+// asUnmarshaler reports whether goValue, or a pointer to it, implements
+// Unmarshaler.
+func asUnmarshaler(goValue reflect.Value) (Unmarshaler, bool) {
+	switch goValue.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Invalid:
+		return nil, false
+	}
+	if goValue.CanAddr() && goValue.Addr().CanInterface() {
+		if um, ok := goValue.Addr().Interface().(Unmarshaler); ok {
+			return um, true
+		}
+	}
+	return nil, false
+}
+
+// unmarshalHashMap adds the entries of a map accepted by isHashMap to
+// goValue, unmarshaling each key into the map's key type and each value into
+// its element type.
+func (u *unmarshaler) unmarshalHashMap(javaObj Object, goValue reflect.Value) error {
+	if goValue.IsNil() {
+		goValue.Set(reflect.MakeMap(goValue.Type()))
+	}
+	keys, values := mapEntries(javaObj)
+	for i := range keys {
+		entry := "[" + strconv.Itoa(i) + "]"
+		key := reflect.New(goValue.Type().Key()).Elem()
+		u.enter(entry+".key", entry+".key")
+		err := u.unmarshalValue(keys[i], key)
+		if err == nil && !isHashable(key) {
+			err = u.locate(fmt.Errorf("unhashable map key of type %s", key.Elem().Type()))
+		}
+		u.leave()
+		if err = u.collect(err); err != nil {
+			return err
+		}
+
+		itm := reflect.New(goValue.Type().Elem()).Elem()
+		u.enter(entry+".value", fmt.Sprintf("[%#v]", key.Interface()))
+		u.mapValues++
+		err = u.unmarshalValue(values[i], itm)
+		u.mapValues--
+		u.leave()
+		if err = u.collect(err); err != nil {
+			return err
+		}
+		goValue.SetMapIndex(key, itm)
+	}
+	return nil
+}
+
+// isHashable reports whether key, which may hold a value of any type if it is
+// an interface, can be used as a map key.
+func isHashable(key reflect.Value) bool {
+	if key.Kind() != reflect.Interface || key.IsNil() {
+		return true
+	}
+	return key.Elem().Type().Comparable()
+}
 
-	if !strings.Contains(fieldName, ".") {
-		pkgPath := goType.PkgPath()
-		for _, p := range packagePrefixes {
-			if strings.HasPrefix(pkgPath, p) {
-				pkgPath = strings.TrimPrefix(pkgPath, p)
-				break
+// toGeneric converts decoded content into plain Go values, the way
+// encoding/json decodes into interface{}: primitives and boxed primitives
+// become scalars, objects become map[string]any, arrays become []any and
+// enums become their constant names. A java.util.HashMap becomes a
+// map[string]any of its entries, with keys that are not strings formatted as
+// by fmt.Sprint. An object referenced more than once becomes a single map.
+func (u *unmarshaler) toGeneric(javaValue Content) any {
+	switch v := javaValue.(type) {
+	case Object:
+		if val, ok := unmarshalWrappedPrimitive(v); ok {
+			return val
+		}
+		id, shared := identityOf(v)
+		if g, ok := u.generic[id]; shared && ok {
+			return g
+		}
+		if isArrayList(v) {
+			values := listElements(v)
+			s := make([]any, len(values))
+			u.shareGeneric(id, shared, s)
+			for i, itm := range values {
+				s[i] = u.toGeneric(itm)
 			}
+			return s
+		}
+		if isHashMap(v) {
+			keys, values := mapEntries(v)
+			m := make(map[string]any, len(keys))
+			u.shareGeneric(id, shared, m)
+			for i, k := range keys {
+				m[genericKey(u.toGeneric(k))] = u.toGeneric(values[i])
+			}
+			return m
+		}
+		m := make(map[string]any)
+		u.shareGeneric(id, shared, m)
+		for name, f := range mergedFields(v) {
+			m[name] = u.toGeneric(f)
+		}
+		return m
+	case Array:
+		s := make([]any, v.Length())
+		for i := range s {
+			s[i] = u.toGeneric(v.Get(i))
+		}
+		return s
+	case []Annotation:
+		s := make([]any, len(v))
+		for i, itm := range v {
+			s[i] = u.toGeneric(itm)
+		}
+		return s
+	case Enum:
+		return v.ConstantName
+	case *Class:
+		if v == nil {
+			return nil
+		}
+		return v.ClassName
+	case BlockData:
+		return []byte(v)
+	default:
+		return v
+	}
+}
+
+// share records p as the Go pointer for key, unless one is already recorded.
+func (u *unmarshaler) share(key refKey, p reflect.Value) {
+	if u.refs == nil {
+		u.refs = make(map[refKey]reflect.Value)
+	}
+	if _, ok := u.refs[key]; !ok {
+		u.refs[key] = p
+	}
+}
+
+// shareGeneric records g as the generic value of a shared Java value, so that
+// other references to it, including cyclic ones, resolve to g.
+func (u *unmarshaler) shareGeneric(id identity, shared bool, g any) {
+	if !shared {
+		return
+	}
+	if u.generic == nil {
+		u.generic = make(map[identity]any)
+	}
+	u.generic[id] = g
+}
+
+// identity distinguishes decoded objects and arrays. References to the same
+// object share its ClassData map, and references to the same array share its
+// backing slice.
+type identity struct {
+	ptr uintptr
+	len int
+}
+
+type refKey struct {
+	id     identity
+	goType reflect.Type
+}
+
+func identityOf(javaValue Content) (identity, bool) {
+	switch v := javaValue.(type) {
+	case Object:
+		if v.ClassData != nil {
+			return identity{reflect.ValueOf(v.ClassData).Pointer(), -1}, true
+		}
+	case Array:
+		if v.Data != nil || v.Raw != nil {
+			values := reflect.ValueOf(v.Raw)
+			if v.Data != nil {
+				values = reflect.ValueOf(v.Data)
+			}
+			if values.Len() > 0 {
+				return identity{values.Pointer(), values.Len()}, true
+			}
+			break
+		}
+		// References to an array from its own values see fewer of them, so
+		// Values are identified by their capacity rather than their length.
+		if cap(v.Values) > 0 {
+			return identity{reflect.ValueOf(v.Values).Pointer(), cap(v.Values)}, true
 		}
+	}
+	return identity{}, false
+}
+
+func charsOf(javaValue Content) ([]uint16, bool) {
+	if arr, ok := javaValue.(Array); ok {
+		return arr.Chars()
+	}
+	return nil, false
+}
+
+// copyPrimitiveArray copies the contents of a primitive array into a slice or
+// array with elements of the same kind, without boxing each element. Slices
+// are appended to. It reports false if arr cannot be copied this way, or if
+// the elements decode themselves.
+func (u *unmarshaler) copyPrimitiveArray(arr Array, goValue reflect.Value) bool {
+	data := reflect.ValueOf(arr.data())
+	if !data.IsValid() {
+		return false
+	}
+	elem := goValue.Type().Elem()
+	if hasUnmarshalHook(elem) {
+		return false
+	}
+	from, to := data.Type().Elem().Kind(), elem.Kind()
+	switch {
+	case from == reflect.Uint8 && to == reflect.Uint8:
+		if !u.opts.allowInt8ToByteCoercion {
+			return false
+		}
+	case from == reflect.Uint8 && to == reflect.Int8:
+	case from != to:
+		return false
+	}
+
+	n := data.Len()
+	dst := goValue
+	if goValue.Kind() == reflect.Slice {
+		start := goValue.Len()
+		goValue.Set(reflect.AppendSlice(goValue, reflect.MakeSlice(goValue.Type(), n, n)))
+		dst = goValue.Slice(start, start+n)
+	}
+	if elem == data.Type().Elem() {
+		reflect.Copy(dst, data)
+		return true
+	}
+	for i := 0; i < n; i++ {
+		src := data.Index(i)
+		switch to {
+		case reflect.Bool:
+			dst.Index(i).SetBool(src.Bool())
+		case reflect.Int8:
+			dst.Index(i).SetInt(int64(int8(src.Uint())))
+		case reflect.Int16, reflect.Int32, reflect.Int64:
+			dst.Index(i).SetInt(src.Int())
+		case reflect.Uint8, reflect.Uint16:
+			dst.Index(i).SetUint(src.Uint())
+		case reflect.Float32, reflect.Float64:
+			dst.Index(i).SetFloat(src.Float())
+		}
+	}
+	return true
+}
+
+// isArrayList reports whether object is a java.util.ArrayList, a subclass of
+// it, or the list returned by java.util.Arrays.asList.
+func isArrayList(object Object) bool {
+	return object.IsInstanceOf("java.util.ArrayList") || object.IsInstanceOf(arraysArrayList)
+}
+
+const arraysArrayList = "java.util.Arrays$ArrayList"
 
-		pkgPath = strings.TrimPrefix(pkgPath, "/")
-		typePath := path.Join(pkgPath, goType.Name())
-		className := strings.ReplaceAll(typePath, "/", ".")
-		if className == "" {
-			return nil, fmt.Errorf("unable to determine class name for field %#v", fieldName)
+// listElements returns the elements of a list accepted by isArrayList. The
+// writeObject method of java.util.ArrayList writes the capacity as block data,
+// followed by each element; java.util.Arrays$ArrayList keeps its elements in
+// the array field a.
+func listElements(object Object) []Value {
+	if object.IsInstanceOf(arraysArrayList) {
+		a, _ := object.ClassData[arraysArrayList]["a"].(Array)
+		values := make([]Value, a.Length())
+		for i := range values {
+			values[i] = a.Get(i)
 		}
+		return values
+	}
+	var values []Value
+	for _, a := range object.GetAnnotation("java.util.ArrayList") {
+		if _, ok := a.(BlockData); ok {
+			continue
+		}
+		values = append(values, a)
+	}
+	return values
+}
+
+// isHashMap reports whether object is a java.util.HashMap or a subclass of it,
+// such as java.util.LinkedHashMap.
+func isHashMap(object Object) bool {
+	return object.IsInstanceOf("java.util.HashMap")
+}
 
-		fieldName = strings.Join([]string{className, fieldName}, ".")
+// mapEntries returns the keys and values of a map accepted by isHashMap. The
+// writeObject method of java.util.HashMap writes the capacity and size as
+// block data, followed by each key and its value.
+func mapEntries(object Object) (keys, values []Value) {
+	var entries []Value
+	for _, a := range object.GetAnnotation("java.util.HashMap") {
+		if _, ok := a.(BlockData); ok {
+			continue
+		}
+		entries = append(entries, a)
+	}
+	for i := 0; i+1 < len(entries); i += 2 {
+		keys = append(keys, entries[i])
+		values = append(values, entries[i+1])
 	}
+	return keys, values
+}
 
-	value, err := object.GetField(fieldName)
+// genericKey returns the key under which toGeneric stores a map entry whose
+// key is k: k itself if it is a string, and otherwise its default format.
+func genericKey(k any) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	return fmt.Sprint(k)
+}
+
+// mergedFields flattens an object's class data into a single map keyed by
+// field name. Fields declared by subclasses shadow those of superclasses.
+// Object annotations are not fields and are left out.
+func mergedFields(object Object) map[string]Value {
+	var classNames []string
+	for cd := object.ClassDesc; cd != nil; cd = cd.Info.SuperClassDesc {
+		classNames = append(classNames, cd.ClassName)
+	}
+	fields := make(map[string]Value)
+	for i := len(classNames) - 1; i >= 0; i-- {
+		for name, v := range object.ClassData[classNames[i]] {
+			if name == objectAnnotationKey {
+				continue
+			}
+			fields[name] = v
+		}
+	}
+	return fields
+}
+
+// unmarshalStruct fills the fields of goValue from javaObj. Fields whose tag
+// is not fully qualified are read from the class data of className. Embedded
+// structs are filled from the data of a class in the object's hierarchy,
+// which allows a Go type to mirror a Java superclass. Every Java field that
+// is read is recorded in mapped.
+func (u *unmarshaler) unmarshalStruct(javaObj Object, goValue reflect.Value, className string, mapped map[string]bool) error {
+	typ := goValue.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		goField := goValue.Field(i)
+		name, opts := parseTag(sf.Tag.Get("java"))
+		if err := opts.validate(); err != nil {
+			return fmt.Errorf("parseTag: Go field %s: %w", sf.Name, err)
+		}
+		if name == "-" && len(opts) == 0 {
+			continue
+		}
+		if isEmbeddedStruct(sf) && (goField.CanSet() || sf.Type.Kind() == reflect.Struct) {
+			// The exported fields of an unexported embedded struct are
+			// still settable.
+			err := u.unmarshalEmbedded(javaObj, goField, sf, mapped)
+			if err != nil && !(opts.contains("optional") && isMissing(err)) {
+				return err
+			}
+			continue
+		}
+		if !goField.CanSet() {
+			continue
+		}
+		fieldName, err := qualifiedFieldName(className, name)
+		if err != nil {
+			return fmt.Errorf("getField: %w", err)
+		}
+		javaField, err := javaObj.GetField(fieldName)
+		if err != nil {
+			if opts.contains("optional") && isMissing(err) {
+				continue
+			}
+			u.enter(fieldName, sf.Name)
+			err = u.collect(u.locate(fmt.Errorf("getField: %w", err)))
+			u.leave()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		mapped[fieldName] = true
+		u.enter(fieldName, sf.Name)
+		err = u.unmarshalValue(javaField, goField)
+		u.leave()
+		if err = u.collect(err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *unmarshaler) unmarshalEmbedded(javaObj Object, goField reflect.Value, sf reflect.StructField, mapped map[string]bool) error {
+	if goField.Kind() == reflect.Pointer {
+		if goField.IsNil() {
+			v := reflect.New(goField.Type().Elem())
+			if err := u.unmarshalEmbedded(javaObj, v.Elem(), sf, mapped); err != nil {
+				return err
+			}
+			goField.Set(v)
+			return nil
+		}
+		goField = goField.Elem()
+	}
+	if um, ok := asUnmarshaler(goField); ok {
+		// There is no way to tell which fields a custom unmarshaler consumed.
+		mapped[allFieldsMapped] = true
+		return um.UnmarshalJava(javaObj, &UnmarshalState{u})
+	}
+	className, err := u.embeddedClassName(javaObj, sf)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("embeddedClassName: %w", err)
 	}
+	if err := u.checkSerialVersionUID(javaObj, goField.Type(), className); err != nil {
+		return err
+	}
+	return u.unmarshalStruct(javaObj, goField, className, mapped)
+}
 
-	return value, nil
+// checkUnmappedFields returns an error naming every field of javaObj that is
+// not in mapped.
+func checkUnmappedFields(javaObj Object, mapped map[string]bool) error {
+	if mapped[allFieldsMapped] {
+		return nil
+	}
+	var unmapped []string
+	for className, fields := range javaObj.ClassData {
+		for name := range fields {
+			fieldName := className + "." + name
+			if name != objectAnnotationKey && !mapped[fieldName] {
+				unmapped = append(unmapped, fieldName)
+			}
+		}
+	}
+	if len(unmapped) > 0 {
+		sort.Strings(unmapped)
+		return fmt.Errorf("%s: %w", strings.Join(unmapped, ", "), ErrUnmappedField)
+	}
+	return nil
+}
+
+const allFieldsMapped = "*"
+
+func isMissing(err error) bool {
+	return errors.Is(err, ErrNoSuchField) || errors.Is(err, ErrNoSuchClass)
 }
 
-var packagePrefixes = []string{"github.com/edutko/cafegopher"}
+type tagOptions []string
+
+// parseTag splits a java struct tag into the field name and its options,
+// e.g. "value,optional".
+func parseTag(tag string) (string, tagOptions) {
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:]
+}
+
+// validate returns an error for the first option that is not known, so that
+// a misspelled option is not silently ignored.
+func (o tagOptions) validate() error {
+	for _, opt := range o {
+		if opt != "optional" && opt != "required" && !strings.HasPrefix(opt, "serialVersionUID=") {
+			return fmt.Errorf("unknown tag option %q", opt)
+		}
+	}
+	return nil
+}
+
+func (o tagOptions) contains(option string) bool {
+	for _, opt := range o {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmbeddedStruct(sf reflect.StructField) bool {
+	if !sf.Anonymous {
+		return false
+	}
+	t := sf.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// embeddedClassName selects the class in the object's hierarchy that an
+// embedded struct maps to: the class named by its tag if present, otherwise
+// the class declared or derived for its type, otherwise the class whose simple
+// name matches the type's name.
+func (u *unmarshaler) embeddedClassName(object Object, sf reflect.StructField) (string, error) {
+	t := sf.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	tagName, _ := parseTag(sf.Tag.Get("java"))
+	names := []string{tagName}
+	if names[0] == "" {
+		if name, ok := u.declaredClassName(t); ok {
+			names = []string{name}
+		} else {
+			names = []string{u.classNameForType(t), t.Name()}
+		}
+	}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, ok := object.ClassData[name]; ok {
+			return name, nil
+		}
+		for cd := object.ClassDesc; cd != nil; cd = cd.Info.SuperClassDesc {
+			if simpleClassName(cd.ClassName) == name {
+				return cd.ClassName, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%#v: %w", names[len(names)-1], ErrNoSuchClass)
+}
+
+// ClassNamer is implemented by Go types that declare the Java class they
+// represent. The method is called on the zero value of the type.
+type ClassNamer interface {
+	JavaClassName() string
+}
+
+// declaredClassName returns the Java class name explicitly declared for a Go
+// struct type, either by a JavaClassName method, by the tag of a blank field
+// (e.g. `_ struct{} java:"com.acme.Dog"`), or by registering the type.
+func (u *unmarshaler) declaredClassName(goType reflect.Type) (string, bool) {
+	if goType.Implements(classNamerType) || reflect.PointerTo(goType).Implements(classNamerType) {
+		if name := reflect.New(goType).Interface().(ClassNamer).JavaClassName(); name != "" {
+			return name, true
+		}
+	}
+	if goType.Kind() == reflect.Struct {
+		for i := 0; i < goType.NumField(); i++ {
+			sf := goType.Field(i)
+			if name, _ := parseTag(sf.Tag.Get("java")); sf.Name == "_" && name != "" {
+				return name, true
+			}
+		}
+	}
+	return u.registeredClassName(goType)
+}
+
+var classNamerType = reflect.TypeOf((*ClassNamer)(nil)).Elem()
+
+var (
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// hasUnmarshalHook reports whether t, or a pointer to it, implements
+// Unmarshaler or encoding.TextUnmarshaler. Converters are not checked: they
+// are registered for classes, so they never apply to primitive values.
+func hasUnmarshalHook(t reflect.Type) bool {
+	for _, h := range []reflect.Type{unmarshalerType, textUnmarshalerType} {
+		if t.Implements(h) || reflect.PointerTo(t).Implements(h) {
+			return true
+		}
+	}
+	return false
+}
+
+// simpleClassName strips the package and any enclosing classes from a binary
+// class name, e.g. "com.edutko.Main$Foo" becomes "Foo".
+func simpleClassName(className string) string {
+	className = className[strings.LastIndex(className, ".")+1:]
+	return className[strings.LastIndex(className, "$")+1:]
+}
+
+// classNameForType derives a Java class name from a Go type's package path
+// (minus any registered package prefix) and name.
+func (u *unmarshaler) classNameForType(goType reflect.Type) string {
+	pkgPath := goType.PkgPath()
+	for _, p := range u.opts.packagePrefixes {
+		if strings.HasPrefix(pkgPath, p) {
+			pkgPath = strings.TrimPrefix(pkgPath, p)
+			break
+		}
+	}
+
+	pkgPath = strings.TrimPrefix(pkgPath, "/")
+	typePath := path.Join(pkgPath, goType.Name())
+	return strings.ReplaceAll(typePath, "/", ".")
+}
+
+// qualifiedFieldName prefixes fieldName with className unless it is already
+// of the form "className.fieldName".
+func qualifiedFieldName(className string, fieldName string) (string, error) {
+	if strings.Contains(fieldName, ".") {
+		return fieldName, nil
+	}
+	if className == "" {
+		return "", fmt.Errorf("unable to determine class name for field %#v", fieldName)
+	}
+	return strings.Join([]string{className, fieldName}, "."), nil
+}
+
+func (u *unmarshaler) enter(javaElem string, goElem string) {
+	u.javaPath = append(u.javaPath, javaElem)
+	u.goPath = append(u.goPath, goElem)
+}
+
+func (u *unmarshaler) enterIndex(i int) {
+	idx := "[" + strconv.Itoa(i) + "]"
+	u.enter(idx, idx)
+}
+
+func (u *unmarshaler) leave() {
+	u.javaPath = u.javaPath[:len(u.javaPath)-1]
+	u.goPath = u.goPath[:len(u.goPath)-1]
+}
+
+func (u *unmarshaler) typeError(javaValue Content, goType reflect.Type) error {
+	return &UnmarshalTypeError{
+		JavaPath: joinPath(u.javaPath, "/"),
+		GoPath:   joinPath(u.goPath, "."),
+		JavaType: javaTypeName(javaValue),
+		GoType:   goType,
+	}
+}
+
+// locate wraps err in an UnmarshalError giving the current path, unless err
+// already carries a path or the path is empty.
+func (u *unmarshaler) locate(err error) error {
+	if err == nil || len(u.javaPath) == 0 && len(u.goPath) == 0 {
+		return err
+	}
+	var ute *UnmarshalTypeError
+	var ue *UnmarshalError
+	if errors.As(err, &ute) || errors.As(err, &ue) {
+		return err
+	}
+	return &UnmarshalError{
+		JavaPath: joinPath(u.javaPath, "/"),
+		GoPath:   joinPath(u.goPath, "."),
+		Err:      err,
+	}
+}
+
+// collect records err and returns nil if the unmarshaler is collecting
+// errors; otherwise it returns err unchanged.
+func (u *unmarshaler) collect(err error) error {
+	if err == nil || !u.opts.collectErrors {
+		return err
+	}
+	u.errs = append(u.errs, err)
+	return nil
+}
+
+// joinPath joins path elements with sep, except that indexes ("[1]") are
+// appended without a separator.
+func joinPath(elems []string, sep string) string {
+	var sb strings.Builder
+	for _, e := range elems {
+		if sb.Len() > 0 && !strings.HasPrefix(e, "[") {
+			sb.WriteString(sep)
+		}
+		sb.WriteString(e)
+	}
+	return sb.String()
+}
+
+// javaTypeName describes the Java type of decoded content, e.g. "int",
+// "java.lang.String" or "com.acme.Widget".
+func javaTypeName(javaValue Content) string {
+	switch v := javaValue.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int8:
+		return "byte"
+	case rune:
+		return "char"
+	case float64:
+		return "double"
+	case float32:
+		return "float"
+	case int:
+		return "int"
+	case int64:
+		return "long"
+	case int16:
+		return "short"
+	case string:
+		return "java.lang.String"
+	case Object, Array, Enum:
+		if cd := classDescOf(v); cd != nil {
+			return cd.ClassName
+		}
+	case *Class:
+		return "java.lang.Class"
+	case BlockData:
+		return "block data"
+	}
+	return fmt.Sprintf("%T", javaValue)
+}
+
+func sortedKeys(m map[string]Value) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
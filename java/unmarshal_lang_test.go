@@ -0,0 +1,147 @@
+package java_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edutko/cafegopher/java"
+	javalang "github.com/edutko/cafegopher/java/lang"
+)
+
+func TestUnmarshal_lang(t *testing.T) {
+	var name string
+	var expected any
+
+	name = "Boolean"
+	expected = javalang.Boolean{Value: true}
+	t.Run(name, func(t *testing.T) {
+		var actual javalang.Boolean
+		assert.Nil(t, java.Unmarshal(readTestdata(name), &actual))
+		assert.Equal(t, expected, actual)
+	})
+
+	name = "Byte"
+	expected = javalang.Byte{Value: -1}
+	t.Run(name, func(t *testing.T) {
+		var actual javalang.Byte
+		assert.Nil(t, java.Unmarshal(readTestdata(name), &actual))
+		assert.Equal(t, expected, actual)
+	})
+
+	name = "Character"
+	expected = javalang.Character{Value: 'a'}
+	t.Run(name, func(t *testing.T) {
+		var actual javalang.Character
+		assert.Nil(t, java.Unmarshal(readTestdata(name), &actual))
+		assert.Equal(t, expected, actual)
+	})
+
+	name = "Double"
+	expected = javalang.Double{Value: 2.718}
+	t.Run(name, func(t *testing.T) {
+		var actual javalang.Double
+		assert.Nil(t, java.Unmarshal(readTestdata(name), &actual))
+		assert.Equal(t, expected, actual)
+	})
+
+	name = "Float"
+	expected = javalang.Float{Value: 3.14}
+	t.Run(name, func(t *testing.T) {
+		var actual javalang.Float
+		assert.Nil(t, java.Unmarshal(readTestdata(name), &actual))
+		assert.Equal(t, expected, actual)
+	})
+
+	name = "Integer"
+	expected = javalang.Integer{Value: 1234567890}
+	t.Run(name, func(t *testing.T) {
+		var actual javalang.Integer
+		assert.Nil(t, java.Unmarshal(readTestdata(name), &actual))
+		assert.Equal(t, expected, actual)
+	})
+
+	name = "Long"
+	expected = javalang.Long{Value: 9876543210}
+	t.Run(name, func(t *testing.T) {
+		var actual javalang.Long
+		assert.Nil(t, java.Unmarshal(readTestdata(name), &actual))
+		assert.Equal(t, expected, actual)
+	})
+
+	name = "Short"
+	expected = javalang.Short{Value: 32767}
+	t.Run(name, func(t *testing.T) {
+		var actual javalang.Short
+		assert.Nil(t, java.Unmarshal(readTestdata(name), &actual))
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func readTestdata(name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", name+".ser"))
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func TestUnmarshal_langWrapper(t *testing.T) {
+	t.Run("primitive into wrapper", func(t *testing.T) {
+		var actual javalang.Integer
+		assert.Nil(t, java.UnmarshalContent(42, &actual))
+		assert.Equal(t, javalang.Integer{Value: 42}, actual)
+	})
+
+	t.Run("null", func(t *testing.T) {
+		actual := javalang.Integer{Value: 7}
+		assert.Nil(t, java.UnmarshalContent(nil, &actual))
+		assert.Equal(t, javalang.Integer{Value: 7}, actual)
+	})
+
+	t.Run("wrapper field", func(t *testing.T) {
+		var actual struct {
+			Value javalang.Long `java:"java.lang.Long.value"`
+		}
+		assert.Nil(t, java.Unmarshal(readTestdata("Long"), &actual))
+		assert.Equal(t, javalang.Long{Value: 9876543210}, actual.Value)
+	})
+
+	t.Run("wrong class", func(t *testing.T) {
+		var actual javalang.Boolean
+		err := java.Unmarshal(readTestdata("Integer"), &actual)
+		var ute *java.UnmarshalTypeError
+		assert.ErrorAs(t, err, &ute)
+		assert.Equal(t, "java.lang.Integer", ute.JavaType)
+	})
+
+	t.Run("converter", func(t *testing.T) {
+		r := java.NewRegistry()
+		java.RegisterConverter(r, "java.lang.Integer", func(c java.Content) (javalang.Integer, error) {
+			return javalang.Integer{Value: -1}, nil
+		})
+		java.RegisterConverter(r, "java.lang.Integer", func(c java.Content) (int, error) {
+			return -2, nil
+		})
+
+		var wrapper javalang.Integer
+		assert.Nil(t, r.Unmarshal(readTestdata("Integer"), &wrapper))
+		assert.Equal(t, javalang.Integer{Value: -1}, wrapper)
+
+		var i int
+		assert.Nil(t, r.Unmarshal(readTestdata("Integer"), &i))
+		assert.Equal(t, -2, i)
+	})
+
+	t.Run("options and paths", func(t *testing.T) {
+		var actual struct {
+			Value javalang.Short `java:"java.lang.Long.value"`
+			Name  javalang.Byte  `java:"java.lang.Long.value"`
+		}
+		err := java.UnmarshalWithOptions(readTestdata("Long"), &actual, java.WithCollectErrors())
+		assert.EqualError(t, err, "unmarshalValue: cannot cast long to int16 at java.lang.Long.value (Go field Value)\n"+
+			"cannot cast long to int8 at java.lang.Long.value (Go field Name)")
+	})
+}
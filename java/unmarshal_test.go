@@ -1,36 +1,25 @@
 package java
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-
-	javalang "github.com/edutko/cafegopher/java/lang"
 )
 
 func TestUnmarshal(t *testing.T) {
 	var name string
 	var expected any
 
-	name = "Boolean"
-	expected = javalang.Boolean{Value: true}
-	t.Run(name, func(t *testing.T) {
-		var actual javalang.Boolean
-		assert.Nil(t, Unmarshal(mustReadFile(name), &actual))
-		assert.Equal(t, expected, actual)
-	})
-
 	name = "Byte"
-	expected = javalang.Byte{Value: -1}
-	t.Run(name, func(t *testing.T) {
-		var actual javalang.Byte
-		assert.Nil(t, Unmarshal(mustReadFile(name), &actual))
-		assert.Equal(t, expected, actual)
-	})
-
 	expected = byte(255)
 	t.Run("Byte as byte", func(t *testing.T) {
 		var actual byte
@@ -38,50 +27,10 @@ func TestUnmarshal(t *testing.T) {
 		assert.Equal(t, expected, actual)
 	})
 
-	name = "Character"
-	expected = javalang.Character{Value: 'a'}
-	t.Run(name, func(t *testing.T) {
-		var actual javalang.Character
-		assert.Nil(t, Unmarshal(mustReadFile(name), &actual))
-		assert.Equal(t, expected, actual)
-	})
-
-	name = "Double"
-	expected = javalang.Double{Value: 2.718}
+	name = "ArrayList"
+	expected = []int{0xaaaaaa, 0xbbbbbb, 0xcccccc, 0xdddddd}
 	t.Run(name, func(t *testing.T) {
-		var actual javalang.Double
-		assert.Nil(t, Unmarshal(mustReadFile(name), &actual))
-		assert.Equal(t, expected, actual)
-	})
-
-	name = "Float"
-	expected = javalang.Float{Value: 3.14}
-	t.Run(name, func(t *testing.T) {
-		var actual javalang.Float
-		assert.Nil(t, Unmarshal(mustReadFile(name), &actual))
-		assert.Equal(t, expected, actual)
-	})
-
-	name = "Integer"
-	expected = javalang.Integer{Value: 1234567890}
-	t.Run(name, func(t *testing.T) {
-		var actual javalang.Integer
-		assert.Nil(t, Unmarshal(mustReadFile(name), &actual))
-		assert.Equal(t, expected, actual)
-	})
-
-	name = "Long"
-	expected = javalang.Long{Value: 9876543210}
-	t.Run(name, func(t *testing.T) {
-		var actual javalang.Long
-		assert.Nil(t, Unmarshal(mustReadFile(name), &actual))
-		assert.Equal(t, expected, actual)
-	})
-
-	name = "Short"
-	expected = javalang.Short{Value: 32767}
-	t.Run(name, func(t *testing.T) {
-		var actual javalang.Short
+		var actual []int
 		assert.Nil(t, Unmarshal(mustReadFile(name), &actual))
 		assert.Equal(t, expected, actual)
 	})
@@ -180,6 +129,688 @@ func TestUnmarshal(t *testing.T) {
 	})
 }
 
+func TestUnmarshal_generic(t *testing.T) {
+	fooMap := map[string]any{
+		"b":    int8(0x7f),
+		"bool": true,
+		"c":    'e',
+		"d":    3.14,
+		"f":    float32(2.718),
+		"i":    7,
+		"l":    int64(5000000000),
+		"s":    int16(32767),
+		"o":    "hello",
+		"a":    []any{int8(0x11), int8(0x22), int8(0x33)},
+		"bars": []any{
+			map[string]any{"value": 0x1111},
+			map[string]any{"value": 0x2222},
+		},
+		"prefix": "KILO",
+		"status": "SNAFU",
+	}
+
+	t.Run("object as any", func(t *testing.T) {
+		var actual any
+		assert.Nil(t, Unmarshal(mustReadFile("object"), &actual))
+		assert.Equal(t, fooMap, actual)
+	})
+
+	t.Run("object as map[string]any", func(t *testing.T) {
+		var actual map[string]any
+		assert.Nil(t, Unmarshal(mustReadFile("object"), &actual))
+		assert.Equal(t, fooMap, actual)
+	})
+
+	t.Run("superclass fields", func(t *testing.T) {
+		var actual map[string]any
+		assert.Nil(t, Unmarshal(mustReadFile("SealedObjectForKeyProtector"), &actual))
+		assert.Equal(t, "PBEWithMD5AndTripleDES", actual["sealAlg"])
+		assert.Len(t, actual["encodedParams"], 17)
+	})
+
+	t.Run("ArrayList as any", func(t *testing.T) {
+		var actual any
+		assert.Nil(t, Unmarshal(mustReadFile("ArrayList"), &actual))
+		assert.Equal(t, []any{0xaaaaaa, 0xbbbbbb, 0xcccccc, 0xdddddd}, actual)
+	})
+
+	t.Run("ArrayList as map[string]any", func(t *testing.T) {
+		var actual map[string]any
+		assert.Nil(t, Unmarshal(mustReadFile("ArrayList"), &actual))
+		assert.Equal(t, map[string]any{"size": 4}, actual)
+	})
+
+	t.Run("HashMap as any", func(t *testing.T) {
+		var actual any
+		assert.Nil(t, Unmarshal(mustReadFile("HashMap"), &actual))
+		assert.Equal(t, map[string]any{"a": 1, "b": 2}, actual)
+	})
+
+	t.Run("HashMap as map[string]int", func(t *testing.T) {
+		actual := map[string]int{"c": 3}
+		assert.Nil(t, Unmarshal(mustReadFile("HashMap"), &actual))
+		assert.Equal(t, map[string]int{"a": 1, "b": 2, "c": 3}, actual)
+	})
+
+	t.Run("HashMap as map[any]any", func(t *testing.T) {
+		var actual map[any]any
+		assert.Nil(t, Unmarshal(mustReadFile("HashMap"), &actual))
+		assert.Equal(t, map[any]any{"a": 1, "b": 2}, actual)
+	})
+
+	t.Run("HashMap with mismatched keys", func(t *testing.T) {
+		var actual map[int]int
+		err := Unmarshal(mustReadFile("HashMap"), &actual)
+		assert.Equal(t, "unmarshalValue: cannot cast java.lang.String to int at [0].key (Go field [0].key)", err.Error())
+	})
+
+	t.Run("HashMap with mismatched values", func(t *testing.T) {
+		var actual map[string]string
+		err := Unmarshal(mustReadFile("HashMap"), &actual)
+		assert.Equal(t, `unmarshalValue: cannot cast java.lang.Integer to string at [0].value (Go field ["a"])`, err.Error())
+	})
+
+	t.Run("null as map", func(t *testing.T) {
+		actual := map[string]any{"a": 1}
+		assert.Nil(t, Unmarshal([]byte{0xac, 0xed, 0x00, 0x05, byte(tcNull)}, &actual))
+		assert.Nil(t, actual)
+	})
+
+	t.Run("strings as []any", func(t *testing.T) {
+		var actual []any
+		assert.Nil(t, Unmarshal(mustReadFile("strings"), &actual))
+		assert.Equal(t, []any{"abc", "def", "ghi"}, actual)
+	})
+
+	t.Run("Integer as any", func(t *testing.T) {
+		var actual any
+		assert.Nil(t, Unmarshal(mustReadFile("Integer"), &actual))
+		assert.Equal(t, 1234567890, actual)
+	})
+
+	t.Run("Character as any", func(t *testing.T) {
+		var actual any
+		assert.Nil(t, Unmarshal(mustReadFile("Character"), &actual))
+		assert.Equal(t, 'a', actual)
+	})
+
+	t.Run("enum as any", func(t *testing.T) {
+		var actual any
+		assert.Nil(t, Unmarshal(mustReadFile("enum"), &actual))
+		assert.Equal(t, "FUBAR", actual)
+	})
+
+	t.Run("non-object as map", func(t *testing.T) {
+		var actual map[string]any
+		err := Unmarshal(mustReadFile("string"), &actual)
+		assert.Equal(t, "unmarshalValue: cannot cast java.lang.String to map[string]interface {}", err.Error())
+	})
+}
+
+func TestUnmarshal_unmarshaler(t *testing.T) {
+	t.Run("custom type", func(t *testing.T) {
+		var actual arrayList
+		assert.Nil(t, Unmarshal(mustReadFile("ArrayList"), &actual))
+		assert.Equal(t, arrayList{Capacity: 4, Items: []int{0xaaaaaa, 0xbbbbbb, 0xcccccc, 0xdddddd}}, actual)
+	})
+
+	t.Run("struct field", func(t *testing.T) {
+		var actual struct {
+			Prefix prefix `java:"com.edutko.Main$Foo.prefix"`
+			Status prefix `java:"com.edutko.Main$Foo.status"`
+		}
+		assert.Nil(t, Unmarshal(mustReadFile("object"), &actual))
+		assert.Equal(t, prefix(1000), actual.Prefix)
+		assert.Equal(t, prefix(0), actual.Status)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var actual arrayList
+		err := Unmarshal(mustReadFile("string"), &actual)
+		assert.Equal(t, "unmarshalValue: not an ArrayList", err.Error())
+	})
+}
+
+func TestUnmarshal_enum(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		var actual string
+		assert.Nil(t, Unmarshal(mustReadFile("enum"), &actual))
+		assert.Equal(t, "FUBAR", actual)
+	})
+
+	t.Run("string kind", func(t *testing.T) {
+		var actual struct {
+			Status status `java:"com.edutko.Main$Foo.status"`
+		}
+		assert.Nil(t, Unmarshal(mustReadFile("object"), &actual))
+		assert.Equal(t, status("SNAFU"), actual.Status)
+	})
+
+	t.Run("TextUnmarshaler", func(t *testing.T) {
+		var actual struct {
+			Prefix siPrefix `java:"com.edutko.Main$Foo.prefix"`
+		}
+		assert.Nil(t, Unmarshal(mustReadFile("object"), &actual))
+		assert.Equal(t, siPrefix{Symbol: "k"}, actual.Prefix)
+	})
+
+	r := NewRegistry()
+	r.RegisterEnum("com.edutko.Main$Status", map[string]int64{"SNAFU": 0, "TARFU": 1, "FUBAR": 2})
+	r.RegisterEnum("com.edutko.Main$Prefix", map[string]int64{"KILO": 1000, "MEGA": 1000000})
+
+	t.Run("integer", func(t *testing.T) {
+		var actual []struct {
+			Status uint8 `java:"com.edutko.Main$Foo.status"`
+			Prefix int64 `java:"com.edutko.Main$Foo.prefix"`
+		}
+		err := r.Unmarshal(mustReadFile("objects"), &actual)
+		assert.Equal(t, `unmarshalValue: unknown constant "GIGA" for enum com.edutko.Main$Prefix (valid constants: KILO, MEGA) at [2]/com.edutko.Main$Foo.prefix (Go field [2].Prefix)`, err.Error())
+		assert.Len(t, actual, 2)
+		assert.Equal(t, uint8(0), actual[0].Status)
+		assert.Equal(t, int64(1000), actual[0].Prefix)
+		assert.Equal(t, uint8(1), actual[1].Status)
+		assert.Equal(t, int64(1000000), actual[1].Prefix)
+	})
+
+	t.Run("integer overflow", func(t *testing.T) {
+		var actual struct {
+			Prefix int8 `java:"com.edutko.Main$Foo.prefix"`
+		}
+		err := r.Unmarshal(mustReadFile("object"), &actual)
+		assert.Equal(t, `unmarshalValue: enum constant com.edutko.Main$Prefix.KILO (1000) overflows int8 at com.edutko.Main$Foo.prefix (Go field Prefix)`, err.Error())
+	})
+
+	t.Run("unregistered", func(t *testing.T) {
+		var actual int
+		err := Unmarshal(mustReadFile("enum"), &actual)
+		assert.Equal(t, `unmarshalValue: no constants registered for enum com.edutko.Main$Status`, err.Error())
+	})
+}
+
+func TestUnmarshal_embedded(t *testing.T) {
+	expected := SealedObject{
+		SealAlg:          "PBEWithMD5AndTripleDES",
+		ParamsAlg:        "PBEWithMD5AndTripleDES",
+		EncryptedContent: unhex("dde157cfd1670f6e76efb93953cfdd4737bc24f9098253e8defb40a8e3e428efd025224f3ad8dba5e71bd16eccfa429a21fbcaa4cb8b5050866014fbaf4be4c3cbfe77aecf4438437b054da882b73e766020f83628e5d85d0fd03b5cc36d8ca8b294aa92afb8efdf3d55fb57683b0cd80c43308ce63552c49c05824980ae1e0122bddcb4f4016e9f324b88c535f90fa2ac2e53119a38721d6012af87e7f40522"),
+		EncodedParams:    unhex("300f0408a47298a326aba6500203030d40"),
+	}
+
+	t.Run("by type name", func(t *testing.T) {
+		var actual struct {
+			SealedObject
+		}
+		assert.Nil(t, Unmarshal(mustReadFile("SealedObjectForKeyProtector"), &actual))
+		assert.Equal(t, expected, actual.SealedObject)
+	})
+
+	t.Run("by tag", func(t *testing.T) {
+		var actual struct {
+			*Base `java:"javax.crypto.SealedObject"`
+		}
+		assert.Nil(t, Unmarshal(mustReadFile("SealedObjectForKeyProtector"), &actual))
+		assert.Equal(t, expected, SealedObject(*actual.Base))
+	})
+
+	t.Run("by simple name tag", func(t *testing.T) {
+		var actual struct {
+			Base `java:"SealedObject"`
+		}
+		assert.Nil(t, Unmarshal(mustReadFile("SealedObjectForKeyProtector"), &actual))
+		assert.Equal(t, expected, SealedObject(actual.Base))
+	})
+
+	t.Run("nested class", func(t *testing.T) {
+		var actual struct {
+			Foo
+		}
+		assert.Nil(t, Unmarshal(mustReadFile("object"), &actual))
+		assert.Equal(t, 7, actual.I)
+	})
+
+	t.Run("no such class", func(t *testing.T) {
+		var actual struct {
+			Base
+		}
+		err := Unmarshal(mustReadFile("SealedObjectForKeyProtector"), &actual)
+		assert.Equal(t, `unmarshalValue: embeddedClassName: "Base": no such class in object`, err.Error())
+	})
+}
+
+func TestUnmarshal_declaredClassName(t *testing.T) {
+	t.Run("method", func(t *testing.T) {
+		var actual namedSealedObject
+		assert.Nil(t, Unmarshal(mustReadFile("SealedObjectForKeyProtector"), &actual))
+		assert.Equal(t, "PBEWithMD5AndTripleDES", actual.SealAlg)
+	})
+
+	t.Run("blank field tag", func(t *testing.T) {
+		var actual struct {
+			_ struct{} `java:"com.edutko.Main$Foo"`
+			I int      `java:"i"`
+			O string   `java:"o"`
+		}
+		assert.Nil(t, Unmarshal(mustReadFile("object"), &actual))
+		assert.Equal(t, 7, actual.I)
+		assert.Equal(t, "hello", actual.O)
+	})
+
+	t.Run("registered", func(t *testing.T) {
+		r := NewRegistry()
+		r.Register("com.edutko.Main$Bar", bar{})
+		var actual []struct {
+			Bars []bar `java:"com.edutko.Main$Foo.bars"`
+		}
+		assert.Nil(t, r.Unmarshal(mustReadFile("objects"), &actual))
+		assert.Equal(t, []bar{{0x1111}, {0x2222}}, actual[0].Bars)
+	})
+
+	t.Run("embedded", func(t *testing.T) {
+		var actual struct {
+			_ struct{} `java:"com.sun.crypto.provider.SealedObjectForKeyProtector"`
+			namedSealedObject
+		}
+		assert.Nil(t, Unmarshal(mustReadFile("SealedObjectForKeyProtector"), &actual))
+		assert.Equal(t, "PBEWithMD5AndTripleDES", actual.ParamsAlg)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		var actual namedSealedObject
+		err := Unmarshal(mustReadFile("object"), &actual)
+		assert.ErrorIs(t, err, ErrClassMismatch)
+		assert.Equal(t, `unmarshalValue: "com.edutko.Main$Foo" is not a javax.crypto.SealedObject: object is not an instance of the declared class`, err.Error())
+	})
+}
+
+func TestUnmarshal_tagOptions(t *testing.T) {
+	t.Run("optional", func(t *testing.T) {
+		var actual struct {
+			I       int    `java:"com.edutko.Main$Foo.i,required"`
+			Missing string `java:"com.edutko.Main$Foo.missing,optional"`
+			Class   string `java:"com.edutko.Main$Baz.missing,optional"`
+		}
+		actual.Missing = "unchanged"
+		assert.Nil(t, Unmarshal(mustReadFile("object"), &actual))
+		assert.Equal(t, 7, actual.I)
+		assert.Equal(t, "unchanged", actual.Missing)
+		assert.Equal(t, "", actual.Class)
+	})
+
+	t.Run("optional embedded", func(t *testing.T) {
+		var actual struct {
+			*Base `java:"com.edutko.Main$Baz,optional"`
+			Foo
+		}
+		assert.Nil(t, Unmarshal(mustReadFile("object"), &actual))
+		assert.Nil(t, actual.Base)
+		assert.Equal(t, 7, actual.I)
+	})
+
+	t.Run("ignored", func(t *testing.T) {
+		var actual struct {
+			I     int    `java:"com.edutko.Main$Foo.i"`
+			Extra string `java:"-"`
+		}
+		assert.Nil(t, Unmarshal(mustReadFile("object"), &actual))
+		assert.Equal(t, 7, actual.I)
+	})
+
+	t.Run("unknown option", func(t *testing.T) {
+		var actual struct {
+			I int `java:"com.edutko.Main$Foo.i,optinal"`
+		}
+		err := Unmarshal(mustReadFile("object"), &actual)
+		assert.EqualError(t, err, `unmarshalValue: parseTag: Go field I: unknown tag option "optinal"`)
+	})
+
+	t.Run("type mismatch is not missing", func(t *testing.T) {
+		var actual struct {
+			I bool `java:"com.edutko.Main$Foo.i,optional"`
+		}
+		err := Unmarshal(mustReadFile("object"), &actual)
+		assert.Equal(t, "unmarshalValue: cannot cast int to bool at com.edutko.Main$Foo.i (Go field I)", err.Error())
+	})
+}
+
+func TestDecoder_DisallowUnknownFields(t *testing.T) {
+	t.Run("unmapped", func(t *testing.T) {
+		var actual struct {
+			_ struct{} `java:"com.edutko.Main$Foo"`
+			B byte     `java:"b"`
+			I int      `java:"i"`
+			S string   `java:"o"`
+			X string   `java:"x,optional"`
+		}
+		d := NewDecoder(bytes.NewReader(mustReadFile("object")))
+		d.DisallowUnknownFields()
+		err := d.Unmarshal(&actual)
+		assert.ErrorIs(t, err, ErrUnmappedField)
+		assert.Equal(t, "unmarshalValue: com.edutko.Main$Foo.a, com.edutko.Main$Foo.bars, com.edutko.Main$Foo.bool, "+
+			"com.edutko.Main$Foo.c, com.edutko.Main$Foo.d, com.edutko.Main$Foo.f, com.edutko.Main$Foo.l, "+
+			"com.edutko.Main$Foo.prefix, com.edutko.Main$Foo.s, com.edutko.Main$Foo.status: field not mapped to Go value", err.Error())
+	})
+
+	t.Run("all mapped", func(t *testing.T) {
+		var actual struct {
+			SealedObject
+		}
+		d := NewDecoder(bytes.NewReader(mustReadFile("SealedObjectForKeyProtector")))
+		d.DisallowUnknownFields()
+		assert.Nil(t, d.Unmarshal(&actual))
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		var actual struct {
+			Bars []struct {
+				Other int `java:"com.edutko.Main$Bar.other,optional"`
+			} `java:"com.edutko.Main$Foo.bars"`
+			Rest map[string]any `java:"-"`
+		}
+		d := NewDecoder(bytes.NewReader(mustReadFile("object")))
+		d.DisallowUnknownFields()
+		err := d.Unmarshal(&actual)
+		assert.Equal(t, "unmarshalValue: com.edutko.Main$Bar.value: field not mapped to Go value at com.edutko.Main$Foo.bars[0] (Go field Bars[0])", err.Error())
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		var actual struct {
+			I int `java:"com.edutko.Main$Foo.i"`
+		}
+		assert.Nil(t, NewDecoder(bytes.NewReader(mustReadFile("object"))).Unmarshal(&actual))
+	})
+}
+
+func TestUnmarshal_typeError(t *testing.T) {
+	type badBar struct {
+		Value string `java:"com.edutko.Main$Bar.value"`
+	}
+	type badFoo struct {
+		I    bool     `java:"com.edutko.Main$Foo.i"`
+		O    string   `java:"com.edutko.Main$Foo.o"`
+		Bars []badBar `java:"com.edutko.Main$Foo.bars"`
+		X    int      `java:"com.edutko.Main$Foo.x"`
+	}
+
+	t.Run("paths", func(t *testing.T) {
+		var actual []struct {
+			Bars []badBar `java:"com.edutko.Main$Foo.bars"`
+		}
+		err := Unmarshal(mustReadFile("objects"), &actual)
+		var ute *UnmarshalTypeError
+		assert.ErrorAs(t, err, &ute)
+		assert.Equal(t, &UnmarshalTypeError{
+			JavaPath: "[0]/com.edutko.Main$Foo.bars[0]/com.edutko.Main$Bar.value",
+			GoPath:   "[0].Bars[0].Value",
+			JavaType: "int",
+			GoType:   reflect.TypeOf(""),
+		}, ute)
+	})
+
+	t.Run("first error", func(t *testing.T) {
+		var actual badFoo
+		err := Unmarshal(mustReadFile("object"), &actual)
+		assert.Equal(t, "unmarshalValue: cannot cast int to bool at com.edutko.Main$Foo.i (Go field I)", err.Error())
+	})
+
+	t.Run("collect errors", func(t *testing.T) {
+		var actual badFoo
+		err := UnmarshalWithOptions(mustReadFile("object"), &actual, WithCollectErrors())
+		assert.Equal(t, "unmarshalValue: cannot cast int to bool at com.edutko.Main$Foo.i (Go field I)\n"+
+			"cannot cast int to string at com.edutko.Main$Foo.bars[0]/com.edutko.Main$Bar.value (Go field Bars[0].Value)\n"+
+			"cannot cast int to string at com.edutko.Main$Foo.bars[1]/com.edutko.Main$Bar.value (Go field Bars[1].Value)\n"+
+			`getField: "x": no such field in object at com.edutko.Main$Foo.x (Go field X)`, err.Error())
+		assert.Equal(t, "hello", actual.O)
+		assert.Len(t, actual.Bars, 2)
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		var actual []struct {
+			Bars []struct {
+				Value int `java:"com.edutko.Main$Bar.missing"`
+			} `java:"com.edutko.Main$Foo.bars"`
+		}
+		err := Unmarshal(mustReadFile("objects"), &actual)
+		var ue *UnmarshalError
+		assert.ErrorAs(t, err, &ue)
+		assert.Equal(t, "[0]/com.edutko.Main$Foo.bars[0]/com.edutko.Main$Bar.missing", ue.JavaPath)
+		assert.Equal(t, "[0].Bars[0].Value", ue.GoPath)
+		assert.ErrorIs(t, err, ErrNoSuchField)
+	})
+
+	t.Run("map key", func(t *testing.T) {
+		var actual map[string]int
+		err := Unmarshal(mustReadFile("object"), &actual)
+		assert.Equal(t, `unmarshalValue: cannot cast [B to int at a (Go field ["a"])`, err.Error())
+	})
+}
+
+func TestUnmarshal_primitiveArrays(t *testing.T) {
+	t.Run("[]byte", func(t *testing.T) {
+		var actual []byte
+		assert.Nil(t, Unmarshal(mustReadFile("bytes"), &actual))
+		assert.Equal(t, []byte{0x11, 0x22, 0x33, 0x44}, actual)
+	})
+
+	t.Run("[]int8", func(t *testing.T) {
+		var actual []int8
+		err := UnmarshalContent(Array{ClassDesc: &byteArray, Data: []byte{0x7f, 0x80}}, &actual)
+		assert.Nil(t, err)
+		assert.Equal(t, []int8{127, -128}, actual)
+	})
+
+	t.Run("[]byte without coercion", func(t *testing.T) {
+		var actual []byte
+		err := UnmarshalContent(Array{ClassDesc: &byteArray, Data: []byte{0x7f, 0x80}}, &actual, WithInt8ToByteCoercion(false))
+		assert.EqualError(t, err, "cannot cast byte to uint8 at [1] (Go field [1])")
+	})
+
+	t.Run("[]int32", func(t *testing.T) {
+		var actual []int32
+		assert.Nil(t, Unmarshal(mustReadFile("ints"), &actual))
+		assert.Equal(t, []int32{1, -2, math.MaxInt32}, actual)
+	})
+
+	t.Run("[]int", func(t *testing.T) {
+		var actual []int
+		assert.Nil(t, Unmarshal(mustReadFile("ints"), &actual))
+		assert.Equal(t, []int{1, -2, math.MaxInt32}, actual)
+	})
+
+	t.Run("named element type", func(t *testing.T) {
+		type count int32
+		var actual [3]count
+		assert.Nil(t, Unmarshal(mustReadFile("ints"), &actual))
+		assert.Equal(t, [3]count{1, -2, math.MaxInt32}, actual)
+	})
+
+	t.Run("element type with Unmarshaler", func(t *testing.T) {
+		var actual []decremented
+		assert.Nil(t, Unmarshal(mustReadFile("ints"), &actual))
+		assert.Equal(t, []decremented{0, -3, math.MaxInt32 - 1}, actual)
+	})
+
+	t.Run("array size mismatch", func(t *testing.T) {
+		var actual [2]int32
+		err := Unmarshal(mustReadFile("ints"), &actual)
+		assert.EqualError(t, err, "unmarshalValue: array size mismatch: expected 2, got 3")
+	})
+
+	t.Run("string from char[]", func(t *testing.T) {
+		var actual string
+		assert.Nil(t, Unmarshal(mustReadFile("chars"), &actual))
+		assert.Equal(t, "h\u00e9llo \U0001F600", actual)
+	})
+
+	t.Run("[]uint16", func(t *testing.T) {
+		var actual []uint16
+		assert.Nil(t, Unmarshal(mustReadFile("chars"), &actual))
+		assert.Equal(t, []uint16{'h', 0xe9, 'l', 'l', 'o', ' ', 0xd83d, 0xde00}, actual)
+	})
+
+	t.Run("generic", func(t *testing.T) {
+		var actual any
+		assert.Nil(t, Unmarshal(mustReadFile("ints"), &actual))
+		assert.Equal(t, []any{1, -2, math.MaxInt32}, actual)
+	})
+}
+
+func TestUnmarshal_nestedArrays(t *testing.T) {
+	t.Run("[][]int", func(t *testing.T) {
+		var actual [][]int
+		assert.Nil(t, Unmarshal(mustReadFile("jagged"), &actual))
+		assert.Equal(t, [][]int{{1, 2}, {3}, nil, {}}, actual)
+	})
+
+	t.Run("[][]int32", func(t *testing.T) {
+		var actual [][]int32
+		assert.Nil(t, Unmarshal(mustReadFile("jagged"), &actual))
+		assert.Equal(t, [][]int32{{1, 2}, {3}, nil, {}}, actual)
+	})
+
+	t.Run("[N][M]float64", func(t *testing.T) {
+		var actual [2][3]float64
+		assert.Nil(t, Unmarshal(mustReadFile("matrix"), &actual))
+		assert.Equal(t, [2][3]float64{{1.5, 2, 3}, {4, 5, 6.25}}, actual)
+	})
+
+	t.Run("[][N]float64", func(t *testing.T) {
+		var actual [][3]float64
+		assert.Nil(t, Unmarshal(mustReadFile("matrix"), &actual))
+		assert.Equal(t, [][3]float64{{1.5, 2, 3}, {4, 5, 6.25}}, actual)
+	})
+
+	t.Run("[][]string", func(t *testing.T) {
+		var actual [][]string
+		assert.Nil(t, Unmarshal(mustReadFile("strings2d"), &actual))
+		assert.Equal(t, [][]string{{"a", "b"}, nil, {"c"}}, actual)
+	})
+
+	t.Run("[N][]string", func(t *testing.T) {
+		var actual [3][]string
+		assert.Nil(t, Unmarshal(mustReadFile("strings2d"), &actual))
+		assert.Equal(t, [3][]string{{"a", "b"}, nil, {"c"}}, actual)
+	})
+
+	t.Run("inner size mismatch", func(t *testing.T) {
+		var actual [2][2]float64
+		err := Unmarshal(mustReadFile("matrix"), &actual)
+		assert.EqualError(t, err, "unmarshalValue: array size mismatch: expected 2, got 3 at [0] (Go field [0])")
+	})
+
+	t.Run("generic", func(t *testing.T) {
+		var actual any
+		assert.Nil(t, Unmarshal(mustReadFile("strings2d"), &actual))
+		assert.Equal(t, []any{[]any{"a", "b"}, nil, []any{"c"}}, actual)
+	})
+}
+
+func TestUnmarshal_lists(t *testing.T) {
+	t.Run("subclass", func(t *testing.T) {
+		sub := Object{
+			ClassDesc: &Class{ClassName: "com.acme.Items", Info: ClassDescInfo{SuperClassDesc: &javaUtilArrayList}},
+			ClassData: al.ClassData,
+		}
+		var actual []int
+		assert.Nil(t, newUnmarshaler().unmarshalValue(sub, reflect.ValueOf(&actual)))
+		assert.Equal(t, []int{0xaaaaaa, 0xbbbbbb, 0xcccccc, 0xdddddd}, actual)
+	})
+
+	t.Run("Arrays.asList", func(t *testing.T) {
+		list := Object{
+			ClassDesc: &Class{ClassName: "java.util.Arrays$ArrayList"},
+			ClassData: ClassData{"java.util.Arrays$ArrayList": {
+				"a": Array{Values: []Value{"abc", "def"}},
+			}},
+		}
+		var actual []string
+		assert.Nil(t, newUnmarshaler().unmarshalValue(list, reflect.ValueOf(&actual)))
+		assert.Equal(t, []string{"abc", "def"}, actual)
+
+		var generic any
+		assert.Nil(t, newUnmarshaler().unmarshalValue(list, reflect.ValueOf(&generic)))
+		assert.Equal(t, []any{"abc", "def"}, generic)
+	})
+}
+
+func TestUnmarshal_references(t *testing.T) {
+	t.Run("pointers", func(t *testing.T) {
+		var root node
+		assert.Nil(t, Unmarshal(mustReadFile("Node"), &root))
+		a, b := root.Next, root.Next.Next
+		assert.Equal(t, "a", a.Name)
+		assert.Equal(t, "b", b.Name)
+		assert.Nil(t, root.Parent)
+		assert.Same(t, &root, a.Parent)
+		assert.Same(t, &root, b.Parent)
+		assert.Nil(t, b.Next)
+	})
+
+	t.Run("pointer to pointer", func(t *testing.T) {
+		var root *node
+		assert.Nil(t, Unmarshal(mustReadFile("Node"), &root))
+		assert.Same(t, root, root.Next.Parent)
+		assert.Same(t, root, root.Next.Next.Parent)
+	})
+
+	t.Run("generic", func(t *testing.T) {
+		var root any
+		assert.Nil(t, Unmarshal(mustReadFile("Node"), &root))
+		m := root.(map[string]any)
+		a := m["next"].(map[string]any)
+		assert.Equal(t, reflect.ValueOf(m).Pointer(), reflect.ValueOf(a["parent"]).Pointer())
+	})
+
+	t.Run("pointer to slice element", func(t *testing.T) {
+		type item struct {
+			Name string `java:"com.acme.Item.name"`
+		}
+		itemClass := &Class{ClassName: "com.acme.Item"}
+		x := Object{ClassDesc: itemClass, ClassData: ClassData{"com.acme.Item": {"name": "x"}}}
+		holder := Object{
+			ClassDesc: &Class{ClassName: "com.acme.Holder"},
+			ClassData: ClassData{"com.acme.Holder": {
+				"items": Array{Values: []Value{x}},
+				"first": x,
+			}},
+		}
+
+		var actual struct {
+			Items []item `java:"com.acme.Holder.items"`
+			First *item  `java:"com.acme.Holder.first"`
+		}
+		assert.Nil(t, newUnmarshaler().unmarshalValue(holder, reflect.ValueOf(&actual)))
+		assert.Same(t, &actual.Items[0], actual.First)
+	})
+
+	t.Run("map values are not shared", func(t *testing.T) {
+		type item struct {
+			Name string `java:"com.acme.Item.name"`
+		}
+		x := Object{ClassDesc: &Class{ClassName: "com.acme.Item"}, ClassData: ClassData{"com.acme.Item": {"name": "x"}}}
+		holder := Object{
+			ClassDesc: &Class{ClassName: "com.acme.Holder"},
+			ClassData: ClassData{"com.acme.Holder": {"a": x}},
+		}
+		var byName map[string]item
+		u := newUnmarshaler()
+		assert.Nil(t, u.unmarshalValue(holder, reflect.ValueOf(&byName)))
+		var first *item
+		assert.Nil(t, u.unmarshalValue(x, reflect.ValueOf(&first)))
+		assert.Equal(t, &item{Name: "x"}, first)
+		first.Name = "y"
+		assert.Equal(t, "x", byName["a"].Name)
+	})
+
+	t.Run("cycle through values", func(t *testing.T) {
+		type selfMap map[string]selfMap
+		self := Object{ClassDesc: &Class{ClassName: "com.acme.Self"}, ClassData: ClassData{"com.acme.Self": {}}}
+		self.ClassData["com.acme.Self"]["self"] = self
+
+		var actual selfMap
+		err := newUnmarshaler().unmarshalValue(self, reflect.ValueOf(&actual))
+		assert.EqualError(t, err, `cyclic reference to com.acme.Self cannot be stored in java.selfMap at self (Go field ["self"])`)
+	})
+}
+
 func TestUnmarshal_errors(t *testing.T) {
 	t.Run("nil", func(t *testing.T) {
 		err := Unmarshal(mustReadFile("string"), nil)
@@ -203,7 +834,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val bool `java:"com.edutko.Main$Foo.i"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast int to bool", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast int to bool at com.edutko.Main$Foo.i (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch int8", func(t *testing.T) {
@@ -211,7 +842,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val int8 `java:"com.edutko.Main$Foo.i"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast int to int8", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast int to int8 at com.edutko.Main$Foo.i (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch int16", func(t *testing.T) {
@@ -219,7 +850,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val int16 `java:"com.edutko.Main$Foo.bool"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast bool to int16", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast boolean to int16 at com.edutko.Main$Foo.bool (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch int", func(t *testing.T) {
@@ -227,7 +858,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val int `java:"com.edutko.Main$Foo.bool"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast bool to int", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast boolean to int at com.edutko.Main$Foo.bool (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch rune", func(t *testing.T) {
@@ -235,7 +866,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val rune `java:"com.edutko.Main$Foo.bool"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast bool to int32", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast boolean to int32 at com.edutko.Main$Foo.bool (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch int64", func(t *testing.T) {
@@ -243,7 +874,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val int64 `java:"com.edutko.Main$Foo.bool"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast bool to int64", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast boolean to int64 at com.edutko.Main$Foo.bool (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch uint8", func(t *testing.T) {
@@ -251,7 +882,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val uint8 `java:"com.edutko.Main$Foo.i"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast int to uint8", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast int to uint8 at com.edutko.Main$Foo.i (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch uint16", func(t *testing.T) {
@@ -259,7 +890,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val uint16 `java:"com.edutko.Main$Foo.bool"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast bool to uint16", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast boolean to uint16 at com.edutko.Main$Foo.bool (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch uint", func(t *testing.T) {
@@ -267,7 +898,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val uint `java:"com.edutko.Main$Foo.bool"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast bool to uint", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast boolean to uint at com.edutko.Main$Foo.bool (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch uint32", func(t *testing.T) {
@@ -275,7 +906,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val uint32 `java:"com.edutko.Main$Foo.bool"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast bool to uint32", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast boolean to uint32 at com.edutko.Main$Foo.bool (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch uint64", func(t *testing.T) {
@@ -283,7 +914,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val uint64 `java:"com.edutko.Main$Foo.bool"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast bool to uint64", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast boolean to uint64 at com.edutko.Main$Foo.bool (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch float32", func(t *testing.T) {
@@ -291,7 +922,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val float32 `java:"com.edutko.Main$Foo.i"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast int to float32", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast int to float32 at com.edutko.Main$Foo.i (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch float64", func(t *testing.T) {
@@ -299,7 +930,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val float64 `java:"com.edutko.Main$Foo.i"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast int to float64", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast int to float64 at com.edutko.Main$Foo.i (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch array", func(t *testing.T) {
@@ -307,7 +938,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val [100]string `java:"com.edutko.Main$Foo.i"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast int to array", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast int to [100]string at com.edutko.Main$Foo.i (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch slice", func(t *testing.T) {
@@ -315,7 +946,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val []string `java:"com.edutko.Main$Foo.i"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast int to slice", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast int to []string at com.edutko.Main$Foo.i (Go field Val)", err.Error())
 	})
 
 	t.Run("type mismatch string", func(t *testing.T) {
@@ -323,7 +954,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val string `java:"com.edutko.Main$Foo.i"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, "unmarshalValue: cannot cast int to string", err.Error())
+		assert.Equal(t, "unmarshalValue: cannot cast int to string at com.edutko.Main$Foo.i (Go field Val)", err.Error())
 	})
 
 	t.Run("array size mismatch", func(t *testing.T) {
@@ -345,7 +976,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val bool `java:"nonexistent.foo"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, `unmarshalValue: getField: "nonexistent": no such class in object`, err.Error())
+		assert.Equal(t, `unmarshalValue: getField: "nonexistent": no such class in object at nonexistent.foo (Go field Val)`, err.Error())
 	})
 
 	t.Run("nonexistent field", func(t *testing.T) {
@@ -353,7 +984,7 @@ func TestUnmarshal_errors(t *testing.T) {
 			Val bool `java:"com.edutko.Main$Foo.nonexistent"`
 		}
 		err := Unmarshal(mustReadFile("object"), &actual)
-		assert.Equal(t, `unmarshalValue: getField: "nonexistent": no such field in object`, err.Error())
+		assert.Equal(t, `unmarshalValue: getField: "nonexistent": no such field in object at com.edutko.Main$Foo.nonexistent (Go field Val)`, err.Error())
 	})
 
 	t.Run("untagged field", func(t *testing.T) {
@@ -373,6 +1004,13 @@ func mustReadFile(name string) []byte {
 	return data
 }
 
+type node struct {
+	_      struct{} `java:"com.edutko.Main$Node"`
+	Name   string   `java:"name"`
+	Parent *node    `java:"parent,optional"`
+	Next   *node    `java:"next"`
+}
+
 type foo struct {
 	B    byte    `java:"com.edutko.Main$Foo.b"`
 	Bool bool    `java:"com.edutko.Main$Foo.bool"`
@@ -393,6 +1031,93 @@ type altfoo struct {
 	S uint16 `java:"com.edutko.Main$Foo.s"`
 }
 
+type SealedObject struct {
+	SealAlg          string `java:"sealAlg"`
+	ParamsAlg        string `java:"paramsAlg"`
+	EncryptedContent []byte `java:"encryptedContent"`
+	EncodedParams    []byte `java:"encodedParams"`
+}
+
+type Base SealedObject
+
+type namedSealedObject struct {
+	SealAlg   string `java:"sealAlg"`
+	ParamsAlg string `java:"paramsAlg"`
+}
+
+func (namedSealedObject) JavaClassName() string {
+	return "javax.crypto.SealedObject"
+}
+
+type bar struct {
+	Value int `java:"value"`
+}
+
+type Foo struct {
+	I int `java:"i"`
+}
+
+type arrayList struct {
+	Capacity int
+	Items    []int
+}
+
+func (l *arrayList) UnmarshalJava(c Content, s *UnmarshalState) error {
+	o, ok := c.(Object)
+	if !ok || o.GetClassName() != "java.util.ArrayList" {
+		return errors.New("not an ArrayList")
+	}
+	for _, a := range o.GetAnnotation("java.util.ArrayList") {
+		if b, ok := a.(BlockData); ok {
+			l.Capacity = int(binary.BigEndian.Uint32(b))
+			continue
+		}
+		var i int
+		if err := s.Unmarshal(a, &i); err != nil {
+			return err
+		}
+		l.Items = append(l.Items, i)
+	}
+	return nil
+}
+
+// decremented is an int32 that decodes itself, as one less than its value.
+type decremented int32
+
+func (d *decremented) UnmarshalJava(c Content, s *UnmarshalState) error {
+	var v int32
+	err := s.Unmarshal(c, &v)
+	*d = decremented(v - 1)
+	return err
+}
+
+type prefix int
+
+type status string
+
+type siPrefix struct {
+	Symbol string
+}
+
+func (p *siPrefix) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "KILO":
+		p.Symbol = "k"
+	case "MEGA":
+		p.Symbol = "M"
+	default:
+		return fmt.Errorf("unknown prefix: %s", text)
+	}
+	return nil
+}
+
+func (p *prefix) UnmarshalJava(c Content, _ *UnmarshalState) error {
+	if e, ok := c.(Enum); ok && e.ConstantName == "KILO" {
+		*p = 1000
+	}
+	return nil
+}
+
 type sealedObject struct {
 	SealAlg          string `java:"javax.crypto.SealedObject.sealAlg"`
 	ParamsAlg        string `java:"javax.crypto.SealedObject.paramsAlg"`
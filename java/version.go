@@ -0,0 +1,88 @@
+package java
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SerialVersionUIDer is implemented by Go types that only accept data written
+// by specific versions of their Java class. The method is called on the zero
+// value of the type.
+type SerialVersionUIDer interface {
+	JavaSerialVersionUIDs() []SerialVersionUID
+}
+
+// checkSerialVersionUID verifies that the serialVersionUID of className in
+// javaObj's hierarchy is one that goType accepts. Types that declare no
+// serialVersionUIDs accept any.
+func (u *unmarshaler) checkSerialVersionUID(javaObj Object, goType reflect.Type, className string) error {
+	accepted, ok, err := u.declaredSerialVersionUIDs(goType)
+	if err != nil || !ok {
+		return err
+	}
+
+	var cd *Class
+	for c := javaObj.ClassDesc; c != nil; c = c.Info.SuperClassDesc {
+		if c.ClassName == className {
+			cd = c
+			break
+		}
+	}
+	if cd == nil {
+		return fmt.Errorf("%s: %w", className, ErrNoSuchClass)
+	}
+
+	for _, uid := range accepted {
+		if uid == cd.SerialVersionUID {
+			return nil
+		}
+	}
+	return &IncompatibleClassError{
+		ClassName:        cd.ClassName,
+		SerialVersionUID: cd.SerialVersionUID,
+		Accepted:         accepted,
+		Type:             goType,
+	}
+}
+
+// declaredSerialVersionUIDs returns the serialVersionUIDs accepted by a Go
+// type, declared by a JavaSerialVersionUIDs method, by serialVersionUID
+// options on the tag of a blank field (e.g.
+// `_ struct{} java:"com.acme.Dog,serialVersionUID=1"`), or by registering the
+// type with RegisterVersion. A serialVersionUID option that is not an integer
+// is an error, rather than being ignored.
+func (u *unmarshaler) declaredSerialVersionUIDs(goType reflect.Type) ([]SerialVersionUID, bool, error) {
+	if goType.Implements(serialVersionUIDerType) || reflect.PointerTo(goType).Implements(serialVersionUIDerType) {
+		if uids := reflect.New(goType).Interface().(SerialVersionUIDer).JavaSerialVersionUIDs(); len(uids) > 0 {
+			return uids, true, nil
+		}
+	}
+	if goType.Kind() == reflect.Struct {
+		var uids []SerialVersionUID
+		for i := 0; i < goType.NumField(); i++ {
+			sf := goType.Field(i)
+			if sf.Name != "_" {
+				continue
+			}
+			_, opts := parseTag(sf.Tag.Get("java"))
+			for _, opt := range opts {
+				if v, ok := strings.CutPrefix(opt, "serialVersionUID="); ok {
+					uid, err := strconv.ParseInt(v, 0, 64)
+					if err != nil {
+						return nil, false, fmt.Errorf("%s: invalid serialVersionUID: %w", goType, err)
+					}
+					uids = append(uids, SerialVersionUID(uid))
+				}
+			}
+		}
+		if len(uids) > 0 {
+			return uids, true, nil
+		}
+	}
+	uids, ok := u.registeredSerialVersionUIDs(goType)
+	return uids, ok, nil
+}
+
+var serialVersionUIDerType = reflect.TypeOf((*SerialVersionUIDer)(nil)).Elem()
@@ -0,0 +1,88 @@
+package java
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshal_serialVersionUID(t *testing.T) {
+	t.Run("blank field tag", func(t *testing.T) {
+		var actual struct {
+			_ struct{} `java:"com.edutko.Main$Foo,serialVersionUID=1,serialVersionUID=4038854518001758741"`
+			I int      `java:"i"`
+		}
+		assert.Nil(t, Unmarshal(mustReadFile("object"), &actual))
+		assert.Equal(t, 7, actual.I)
+	})
+
+	t.Run("malformed tag", func(t *testing.T) {
+		var actual struct {
+			_ struct{} `java:"com.edutko.Main$Foo,serialVersionUID=12L"`
+			I int      `java:"i"`
+		}
+		err := Unmarshal(mustReadFile("object"), &actual)
+		assert.ErrorIs(t, err, strconv.ErrSyntax)
+	})
+
+	t.Run("method", func(t *testing.T) {
+		var actual fooV1
+		err := Unmarshal(mustReadFile("object"), &actual)
+		var ice *IncompatibleClassError
+		assert.ErrorAs(t, err, &ice)
+		assert.Equal(t, "unmarshalValue: com.edutko.Main$Foo: incompatible class: stream serialVersionUID = 4038854518001758741, java.fooV1 accepts [1 2]", err.Error())
+	})
+
+	t.Run("embedded superclass", func(t *testing.T) {
+		var actual struct {
+			sealedObjectV2
+		}
+		err := Unmarshal(mustReadFile("SealedObjectForKeyProtector"), &actual)
+		assert.Equal(t, "unmarshalValue: javax.crypto.SealedObject: incompatible class: stream serialVersionUID = 4482838265551344752, java.sealedObjectV2 accepts [2]", err.Error())
+	})
+
+	t.Run("versioned registry", func(t *testing.T) {
+		r := NewRegistry()
+		r.Register("com.edutko.Main$Foo", fooV1{})
+		r.RegisterVersion("com.edutko.Main$Foo", 4038854518001758741, fooV2{})
+
+		var actual []any
+		assert.Nil(t, r.Unmarshal(mustReadFile("objects"), &actual))
+		assert.Equal(t, []any{fooV2{I: 7}, fooV2{I: 8}, fooV2{I: 9}}, actual)
+	})
+
+	t.Run("registered version mismatch", func(t *testing.T) {
+		r := NewRegistry()
+		r.RegisterVersion("com.edutko.Main$Foo", 42, fooV2{})
+
+		var actual fooV2
+		err := r.Unmarshal(mustReadFile("object"), &actual)
+		var ice *IncompatibleClassError
+		assert.ErrorAs(t, err, &ice)
+		assert.Equal(t, &IncompatibleClassError{
+			ClassName:        "com.edutko.Main$Foo",
+			SerialVersionUID: 4038854518001758741,
+			Accepted:         []SerialVersionUID{42},
+			Type:             reflect.TypeOf(actual),
+		}, ice)
+	})
+}
+
+type fooV1 struct {
+	I int `java:"com.edutko.Main$Foo.i"`
+}
+
+func (fooV1) JavaSerialVersionUIDs() []SerialVersionUID {
+	return []SerialVersionUID{1, 2}
+}
+
+type fooV2 struct {
+	I int `java:"com.edutko.Main$Foo.i"`
+}
+
+type sealedObjectV2 struct {
+	_       struct{} `java:"javax.crypto.SealedObject,serialVersionUID=2"`
+	SealAlg string   `java:"sealAlg"`
+}
@@ -0,0 +1,136 @@
+package scan
+
+import (
+	"github.com/edutko/cafegopher/java"
+)
+
+// A Rule flags nodes of the decoded graph. Match returns the name of the
+// offending class and true when the node should be reported.
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+	Match       func(n Node) (string, bool)
+}
+
+// ClassRule matches any object, array, enum or class descriptor whose class
+// or one of its superclasses is in classNames.
+func ClassRule(id string, severity Severity, description string, classNames ...string) Rule {
+	set := toSet(classNames)
+	return Rule{
+		ID:          id,
+		Severity:    severity,
+		Description: description,
+		Match: func(n Node) (string, bool) {
+			for _, name := range n.ClassNames() {
+				if set[name] {
+					return name, true
+				}
+			}
+			return "", false
+		},
+	}
+}
+
+// FieldRule matches objects of one of classNames whose field (declared by
+// that class) holds a value accepted by pred. A nil pred accepts any
+// non-null value.
+func FieldRule(id string, severity Severity, description string, field string, pred func(java.Value) bool, classNames ...string) Rule {
+	if pred == nil {
+		pred = func(v java.Value) bool { return v != nil }
+	}
+	set := toSet(classNames)
+	return Rule{
+		ID:          id,
+		Severity:    severity,
+		Description: description,
+		Match: func(n Node) (string, bool) {
+			o, ok := n.Content.(java.Object)
+			if !ok {
+				return "", false
+			}
+			for _, name := range n.ClassNames() {
+				if !set[name] {
+					continue
+				}
+				if v, ok := o.ClassData[name][field]; ok && pred(v) {
+					return name, true
+				}
+			}
+			return "", false
+		},
+	}
+}
+
+func DefaultRules() []Rule {
+	return []Rule{
+		ClassRule("commons-collections-invoker", Critical,
+			"commons-collections transformer that invokes arbitrary methods or constructors",
+			"org.apache.commons.collections.functors.InvokerTransformer",
+			"org.apache.commons.collections.functors.InstantiateTransformer",
+			"org.apache.commons.collections4.functors.InvokerTransformer",
+			"org.apache.commons.collections4.functors.InstantiateTransformer",
+		),
+		ClassRule("commons-collections-chained", High,
+			"commons-collections transformer chain",
+			"org.apache.commons.collections.functors.ChainedTransformer",
+			"org.apache.commons.collections4.functors.ChainedTransformer",
+		),
+		ClassRule("commons-collections-lazy-map", Medium,
+			"commons-collections map that runs a transformer on access",
+			"org.apache.commons.collections.map.LazyMap",
+			"org.apache.commons.collections.map.TransformedMap",
+			"org.apache.commons.collections.keyvalue.TiedMapEntry",
+			"org.apache.commons.collections4.map.LazyMap",
+			"org.apache.commons.collections4.map.TransformedMap",
+			"org.apache.commons.collections4.keyvalue.TiedMapEntry",
+		),
+		FieldRule("templates-impl-bytecodes", Critical,
+			"TemplatesImpl carrying class bytecode",
+			"_bytecodes", nil,
+			"com.sun.org.apache.xalan.internal.xsltc.trax.TemplatesImpl",
+			"org.apache.xalan.xsltc.trax.TemplatesImpl",
+		),
+		ClassRule("spring-method-invoke-type-provider", High,
+			"Spring type provider that invokes a method on deserialization",
+			"org.springframework.core.SerializableTypeWrapper$MethodInvokeTypeProvider",
+		),
+		ClassRule("groovy-method-closure", High,
+			"Groovy closure bound to an arbitrary method",
+			"org.codehaus.groovy.runtime.MethodClosure",
+			"org.codehaus.groovy.runtime.ConvertedClosure",
+		),
+		FieldRule("bad-attribute-value-exp", High,
+			"BadAttributeValueExpException holding an object, which triggers toString() on deserialization",
+			"val", isObject,
+			"javax.management.BadAttributeValueExpException",
+		),
+		FieldRule("priority-queue-comparator", Medium,
+			"PriorityQueue with a comparator, which is invoked on deserialization",
+			"comparator", nil,
+			"java.util.PriorityQueue",
+			"java.util.concurrent.PriorityBlockingQueue",
+		),
+		ClassRule("jndi-reference", High,
+			"class that can trigger a JNDI lookup",
+			"javax.naming.Reference",
+			"com.sun.jndi.rmi.registry.ReferenceWrapper",
+			"com.sun.jndi.ldap.LdapAttribute",
+			"com.sun.rowset.JdbcRowSetImpl",
+			"javax.management.remote.rmi.RMIConnector",
+		),
+	}
+}
+
+func isObject(v java.Value) bool {
+	_, ok := v.(java.Object)
+	return ok
+}
+
+func toSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[n] = true
+	}
+	return set
+}
@@ -0,0 +1,231 @@
+// Package scan flags known Java deserialization gadget classes in decoded
+// streams. It only inspects the decoded object graph; it never loads,
+// executes, or constructs payloads.
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/edutko/cafegopher/java"
+)
+
+type Severity int
+
+const (
+	Low Severity = iota + 1
+	Medium
+	High
+	Critical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Low:
+		return "low"
+	case Medium:
+		return "medium"
+	case High:
+		return "high"
+	case Critical:
+		return "critical"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+func ParseSeverity(s string) (Severity, error) {
+	for sev := Low; sev <= Critical; sev++ {
+		if strings.EqualFold(s, sev.String()) {
+			return sev, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity: %#v", s)
+}
+
+type Finding struct {
+	RuleID      string
+	Severity    Severity
+	Path        Path
+	ClassName   string
+	Description string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s [%s] %s: %s (%s)", f.Path, f.Severity, f.RuleID, f.Description, f.ClassName)
+}
+
+// Path locates a node in the decoded graph. Elements are either indexes
+// ("[2]") or qualified field names ("java.util.PriorityQueue.comparator").
+type Path []string
+
+func (p Path) String() string {
+	var sb strings.Builder
+	for _, e := range p {
+		if !strings.HasPrefix(e, "[") {
+			sb.WriteString("/")
+		}
+		sb.WriteString(e)
+	}
+	return sb.String()
+}
+
+func (p Path) index(i int) Path {
+	return p.append("[" + strconv.Itoa(i) + "]")
+}
+
+func (p Path) append(elem string) Path {
+	np := make(Path, len(p), len(p)+1)
+	copy(np, p)
+	return append(np, elem)
+}
+
+// Node is a single value visited while walking the decoded graph.
+type Node struct {
+	Path    Path
+	Content java.Content
+}
+
+// ClassNames returns the names of the node's class and all of its
+// superclasses, starting with the most derived class.
+func (n Node) ClassNames() []string {
+	var cd *java.Class
+	switch v := n.Content.(type) {
+	case java.Object:
+		cd = v.ClassDesc
+	case java.Array:
+		cd = v.ClassDesc
+	case java.Enum:
+		cd = v.ClassDesc
+	case *java.Class:
+		cd = v
+	}
+	var names []string
+	for ; cd != nil; cd = cd.Info.SuperClassDesc {
+		names = append(names, cd.ClassName)
+	}
+	return names
+}
+
+type Scanner struct {
+	Rules []Rule
+}
+
+func NewScanner(rules ...Rule) *Scanner {
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+	return &Scanner{Rules: rules}
+}
+
+// Scan walks the given contents (as returned by java.Decoder.DecodeAll, even
+// if decoding stopped early) and returns every rule match. An object or array
+// reachable by several paths is reported once, at the first of them.
+func (s *Scanner) Scan(contents ...java.Content) []Finding {
+	var findings []Finding
+	visited := make(map[uintptr]bool)
+	for i, c := range contents {
+		s.walk(Node{Path: Path{}.index(i), Content: c}, visited, &findings)
+	}
+	return findings
+}
+
+// ScanReader decodes as much of r as possible and scans the result. Findings
+// are returned even when decoding fails; a clean end of stream is not an error.
+func (s *Scanner) ScanReader(r io.Reader) ([]Finding, error) {
+	contents, err := java.NewDecoder(r).DecodeAll(-1)
+	findings := s.Scan(contents...)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return findings, err
+	}
+	return findings, nil
+}
+
+func Scan(contents ...java.Content) []Finding {
+	return NewScanner().Scan(contents...)
+}
+
+// walk visits n and its descendants. Objects and arrays are visited once per
+// scan, at the first path that reaches them, so that cyclic graphs terminate
+// and shared nodes are not walked once for every path to them.
+func (s *Scanner) walk(n Node, visited map[uintptr]bool, findings *[]Finding) {
+	if n.Content == nil {
+		return
+	}
+	if id := identity(n.Content); id != 0 {
+		if visited[id] {
+			return
+		}
+		visited[id] = true
+	}
+
+	for _, r := range s.Rules {
+		if className, ok := r.Match(n); ok {
+			*findings = append(*findings, Finding{
+				RuleID:      r.ID,
+				Severity:    r.Severity,
+				Path:        n.Path,
+				ClassName:   className,
+				Description: r.Description,
+			})
+		}
+	}
+
+	switch v := n.Content.(type) {
+	case java.Object:
+		for _, className := range hierarchy(v.ClassDesc) {
+			fields := v.ClassData[className]
+			for _, name := range sortedKeys(fields) {
+				s.walk(Node{Path: n.Path.append(className + "." + name), Content: fields[name]}, visited, findings)
+			}
+		}
+	case java.Array:
+		for i, item := range v.Values {
+			s.walk(Node{Path: n.Path.index(i), Content: item}, visited, findings)
+		}
+	case []java.Annotation:
+		for i, a := range v {
+			s.walk(Node{Path: n.Path.index(i), Content: a}, visited, findings)
+		}
+	}
+}
+
+// identity returns a value that identifies the decoded node c, or 0 if c is
+// not an object or an array that can be shared.
+func identity(c java.Content) uintptr {
+	switch v := c.(type) {
+	case java.Object:
+		if v.ClassData != nil {
+			return reflect.ValueOf(v.ClassData).Pointer()
+		}
+	case java.Array:
+		if len(v.Values) > 0 {
+			return reflect.ValueOf(v.Values).Pointer()
+		}
+	}
+	return 0
+}
+
+// hierarchy returns class names from the root superclass down, matching the
+// order in which class data appears in the stream.
+func hierarchy(cd *java.Class) []string {
+	var names []string
+	for ; cd != nil; cd = cd.Info.SuperClassDesc {
+		names = append([]string{cd.ClassName}, names...)
+	}
+	return names
+}
+
+func sortedKeys(m map[string]java.Value) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,137 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edutko/cafegopher/java"
+)
+
+func TestScanner_Scan(t *testing.T) {
+	invoker := class("org.apache.commons.collections.functors.InvokerTransformer", nil)
+	chained := class("org.apache.commons.collections.functors.ChainedTransformer", nil)
+	transformers := java.Array{
+		ClassDesc: class("[Lorg.apache.commons.collections.Transformer;", nil),
+		Values:    []java.Value{object(invoker, nil), object(invoker, nil)},
+	}
+	chain := object(chained, map[string]java.Value{"iTransformers": transformers})
+
+	testCases := []struct {
+		name     string
+		contents []java.Content
+		expected []Finding
+	}{
+		{
+			"nested transformers",
+			[]java.Content{"hi", chain},
+			[]Finding{
+				{"commons-collections-chained", High, Path{"[1]"}, chained.ClassName, "commons-collections transformer chain"},
+				{"commons-collections-invoker", Critical, Path{"[1]", chained.ClassName + ".iTransformers", "[0]"}, invoker.ClassName, "commons-collections transformer that invokes arbitrary methods or constructors"},
+				{"commons-collections-invoker", Critical, Path{"[1]", chained.ClassName + ".iTransformers", "[1]"}, invoker.ClassName, "commons-collections transformer that invokes arbitrary methods or constructors"},
+			},
+		},
+		{
+			"superclass",
+			[]java.Content{object(class("com.example.Ref", class("javax.naming.Reference", nil)), nil)},
+			[]Finding{
+				{"jndi-reference", High, Path{"[0]"}, "javax.naming.Reference", "class that can trigger a JNDI lookup"},
+			},
+		},
+		{
+			"TemplatesImpl without bytecodes",
+			[]java.Content{object(class("com.sun.org.apache.xalan.internal.xsltc.trax.TemplatesImpl", nil), map[string]java.Value{"_bytecodes": nil})},
+			nil,
+		},
+		{
+			"TemplatesImpl with bytecodes",
+			[]java.Content{object(class("com.sun.org.apache.xalan.internal.xsltc.trax.TemplatesImpl", nil), map[string]java.Value{"_bytecodes": java.Array{}})},
+			[]Finding{
+				{"templates-impl-bytecodes", Critical, Path{"[0]"}, "com.sun.org.apache.xalan.internal.xsltc.trax.TemplatesImpl", "TemplatesImpl carrying class bytecode"},
+			},
+		},
+		{
+			"BadAttributeValueExpException with string",
+			[]java.Content{object(class("javax.management.BadAttributeValueExpException", nil), map[string]java.Value{"val": "oops"})},
+			nil,
+		},
+		{
+			"PriorityQueue without comparator",
+			[]java.Content{object(class("java.util.PriorityQueue", nil), map[string]java.Value{"comparator": nil, "size": 2})},
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, NewScanner().Scan(tc.contents...))
+		})
+	}
+}
+
+func TestScanner_Scan_customRule(t *testing.T) {
+	s := NewScanner(ClassRule("acme", Low, "acme class", "com.acme.Widget"))
+	findings := s.Scan(java.Enum{ClassDesc: class("com.acme.Widget", nil), ConstantName: "FOO"})
+	assert.Equal(t, []Finding{{"acme", Low, Path{"[0]"}, "com.acme.Widget", "acme class"}}, findings)
+}
+
+func TestScanner_Scan_sharedNodes(t *testing.T) {
+	// Each level refers to the previous one twice, so there are 2^depth paths
+	// to the invoker at the bottom.
+	invoker := class("org.apache.commons.collections.functors.InvokerTransformer", nil)
+	level := class("com.example.Level", nil)
+	node := object(invoker, nil)
+	for i := 0; i < 64; i++ {
+		node = object(level, map[string]java.Value{"a": node, "b": node})
+	}
+
+	done := make(chan []Finding)
+	go func() { done <- NewScanner().Scan(node) }()
+	select {
+	case findings := <-done:
+		assert.Len(t, findings, 1)
+		assert.Len(t, findings[0].Path, 65)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Scan did not finish")
+	}
+}
+
+func TestScanner_ScanReader(t *testing.T) {
+	for _, name := range []string{"ArrayList", "Node", "object", "objects", "strings"} {
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("..", "java", "testdata", name+".ser"))
+			if err != nil {
+				panic(err)
+			}
+			defer f.Close()
+
+			findings, err := NewScanner().ScanReader(f)
+
+			assert.Nil(t, err)
+			assert.Empty(t, findings)
+		})
+	}
+}
+
+func TestPath_String(t *testing.T) {
+	p := Path{"[0]", "java.util.PriorityQueue.queue", "[3]", "[object annotation]"}
+	assert.Equal(t, "[0]/java.util.PriorityQueue.queue[3][object annotation]", p.String())
+}
+
+func class(name string, super *java.Class) *java.Class {
+	return &java.Class{ClassName: name, Info: java.ClassDescInfo{Flags: java.ScSerializable, SuperClassDesc: super}}
+}
+
+func object(c *java.Class, fields map[string]java.Value) java.Object {
+	data := java.ClassData{}
+	for cd := c; cd != nil; cd = cd.Info.SuperClassDesc {
+		data[cd.ClassName] = map[string]java.Value{}
+	}
+	if fields != nil {
+		data[c.ClassName] = fields
+	}
+	return java.Object{ClassDesc: c, ClassData: data}
+}
@@ -70,6 +70,19 @@ public class Main {
         }
     }
 
+    private static class Node implements Serializable {
+        private static final long serialVersionUID = 1L;
+
+        private String name;
+        private Node parent;
+        private Node next;
+
+        protected Node(String name, Node parent) {
+            this.name = name;
+            this.parent = parent;
+        }
+    }
+
     private static void serializeToFile(Object obj, String path, String name) {
         try {
             FileOutputStream o = new FileOutputStream(path + "/" + name + ".ser");
@@ -111,5 +124,17 @@ public class Main {
         serializeToFile(Status.FUBAR, args[0], "enum");
         serializeToFile(new byte[]{0x11, 0x22, 0x33, 0x44}, args[0], "bytes");
         serializeToFile(new String[]{"abc", "def", "ghi"}, args[0], "strings");
+        serializeToFile(new int[]{1, -2, Integer.MAX_VALUE}, args[0], "ints");
+        serializeToFile("h\u00e9llo \ud83d\ude00".toCharArray(), args[0], "chars");
+        serializeToFile(new int[][]{{1, 2}, {3}, null, {}}, args[0], "jagged");
+        serializeToFile(new double[][]{{1.5, 2, 3}, {4, 5, 6.25}}, args[0], "matrix");
+        serializeToFile(new String[][]{{"a", "b"}, null, {"c"}}, args[0], "strings2d");
+
+        Node root = new Node("root", null);
+        Node a = new Node("a", root);
+        Node b = new Node("b", root);
+        root.next = a;
+        a.next = b;
+        serializeToFile(root, args[0], "Node");
     }
 }
\ No newline at end of file